@@ -1,21 +1,23 @@
 package climax
 
 import (
+	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
-)
-
-var (
-	outputDevice io.Writer = os.Stdout
-	errorDevice  io.Writer = os.Stderr
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
 )
 
 // Application is a main CLI instance.
 //
 // By default, Climax provides its own implementation of version
-// command, but it will use "version" command instead if you
-// provide one.
+// and completion commands, but it will use "version" or
+// "completion" command instead if you provide one.
 type Application struct {
 	Name    string // `go`
 	Brief   string // `Go is a tool for managing Go source code.`
@@ -25,12 +27,110 @@ type Application struct {
 	Topics   []Topic
 	Groups   []Group
 
+	// Flags are global options, accepted by every command
+	// either before or after its name.
+	//
+	// Examples: --verbose, --config, --no-color
+	Flags []Flag
+
+	// Config is the configuration file, read on every run.
+	// Nil disables the configuration altogether.
+	//
+	// It also brings in the --config global option, selecting
+	// the file, and the "config" help topic, documenting keys.
+	Config *Config
+
+	// Shell is the interactive shell, run by the built-in "shell"
+	// command, unless you provide one. Nil disables the command.
+	Shell *Shell
+
+	// Scripts enables the built-in "exec" command, unless you
+	// provide one. It runs the commands from the script file, or
+	// the standard input, see RunScript.
+	Scripts bool
+
+	// ResponseFiles enables the @path arguments, standing for the
+	// arguments read from the file, e.g. for the lists too long
+	// for the command line. Nil disables them.
+	ResponseFiles *ResponseFiles
+
+	// EnvPrefix binds every flag to an environment variable named
+	// after it, e.g. the "separator" flag of the app with MYAPP
+	// prefix takes its value from MYAPP_SEPARATOR, unless the flag
+	// is present on the command line.
+	EnvPrefix string
+
+	// SuggestionDistance is the maximum edit distance between the
+	// mistyped name of a command, topic or option and the names
+	// suggested instead. Zero stands for the default of 2, while
+	// a negative value turns the suggestions off.
+	SuggestionDistance int
+
+	// PrefixMatching lets any unambiguous prefix of a command
+	// name, alias or long option name stand for the whole one,
+	// e.g. "app rem ad --verb" for "app remote add --verbose".
+	// Ambiguous prefixes are rejected with the candidates listed.
+	PrefixMatching bool
+
+	// ErrorFormatter renders the errors Run reports to the error
+	// output. By default, the message is prefixed with the name
+	// of the application, and the usage of the command follows
+	// the UsageError ones. Empty messages are not printed.
+	ErrorFormatter func(err error) string
+
+	// ExitCodeMapper maps the errors RunArgs returns to exit codes.
+	// By default, ExitError carries its own code, PanicError means
+	// ExitPanic, cancellation of the context means ExitInterrupted
+	// and the rest are 1.
+	ExitCodeMapper func(err error) int
+
+	// GracePeriod is the time Run gives the command to stop once
+	// SIGINT or SIGTERM cancels its context, before exiting with
+	// ExitInterrupted anyway. Zero means no limit: the command is
+	// only forced to stop by the second signal.
+	GracePeriod time.Duration
+
+	// Middleware wraps the handlers of all the commands, as well
+	// as the default handler. It is the outermost one, wrapping
+	// the middleware of the commands.
+	Middleware []Middleware
+
+	// Before, After and Finally hooks run around the handlers of
+	// all the commands, as well as the default handler, outside
+	// the hooks of the commands. See Command.Before for the order.
+	Before  BeforeHook
+	After   AfterHook
+	Finally FinallyHook
+
+	// RecoverPanics makes the application recover from panics of
+	// handlers and hooks: instead of crashing, the run fails with
+	// PanicError, reported as an internal error, and ExitPanic exit
+	// code, while the stack trace goes to a crash report file.
+	//
+	// Set the DebugEnv environment variable to get the raw panic.
+	RecoverPanics bool
+
+	// CrashDir is the directory of crash reports, the temporary
+	// one is used if it's empty.
+	CrashDir string
+
 	// Default is a default handler. It gets executed if there are
 	// no command line arguments (except the program name), when
 	// otherwise, by default, the help entry is being shown.
 	Default CmdHandler
 
+	// Stdout, Stderr and Stdin are the streams the application
+	// and its commands talk through. Nil values fall back to
+	// os.Stdout, os.Stderr and os.Stdin respectively.
+	Stdout io.Writer
+	Stderr io.Writer
+	Stdin  io.Reader
+
 	ungroupedCmdsCount int
+
+	// input is the standard input the shell shares with the
+	// commands it runs, so neither reads ahead of the other.
+	input *bufio.Reader
 }
 
 // Group connects a list of commands with a descriptive string.
@@ -41,34 +141,159 @@ type Group struct {
 	Commands []*Command
 }
 
+func (a *Application) stdout() io.Writer {
+	if a.Stdout == nil {
+		return os.Stdout
+	}
+
+	return a.Stdout
+}
+
+func (a *Application) stderr() io.Writer {
+	if a.Stderr == nil {
+		return os.Stderr
+	}
+
+	return a.Stderr
+}
+
+func (a *Application) stdin() io.Reader {
+	if a.input != nil {
+		return a.input
+	}
+
+	if a.Stdin == nil {
+		return os.Stdin
+	}
+
+	return a.Stdin
+}
+
 func (a *Application) println(stuff ...interface{}) {
-	fmt.Fprintln(outputDevice, stuff...)
+	fmt.Fprintln(a.stdout(), stuff...)
 }
 
 func (a *Application) printf(format string, stuff ...interface{}) {
-	fmt.Fprintf(outputDevice, format, stuff...)
+	fmt.Fprintf(a.stdout(), format, stuff...)
 }
 
 func (a *Application) printerr(err ...interface{}) {
 	for _, each := range err {
-		fmt.Fprintln(errorDevice, a.Name+":", each)
+		fmt.Fprintln(a.stderr(), a.Name+":", each)
+	}
+}
+
+// builtins are the names of the built-in commands.
+func (a *Application) builtins() []string {
+	builtins := []string{"help", "version", "completion"}
+	if a.Shell != nil {
+		builtins = append(builtins, shellCommandName)
 	}
+
+	if a.Scripts {
+		builtins = append(builtins, execCommandName)
+	}
+
+	return builtins
 }
 
 func (a *Application) commandByName(name string) *Command {
-	for i, command := range a.Commands {
+	return commandByName(a.Commands, name)
+}
+
+func commandByName(commands []Command, name string) *Command {
+	for i, command := range commands {
 		if command.Name == name {
-			return &a.Commands[i]
+			return &commands[i]
+		}
+
+		for _, alias := range command.Aliases {
+			if alias == name {
+				return &commands[i]
+			}
 		}
 	}
 
 	return nil
 }
 
+// resolveCommand returns the name of the command (or one of the
+// built-in ones) the name given stands for: either the very same
+// name, the name of the aliased command or, if prefix matching is
+// on, the one starting with the name. Unknown names are returned
+// as they are.
+func (a *Application) resolveCommand(commands []Command, name string, builtins ...string) (string, error) {
+	if command := commandByName(commands, name); command != nil {
+		return command.Name, nil
+	}
+
+	for _, builtin := range builtins {
+		if builtin == name {
+			return name, nil
+		}
+	}
+
+	if !a.PrefixMatching || name == "" {
+		return name, nil
+	}
+
+	var candidates []string
+	for _, command := range commands {
+		for _, each := range command.names() {
+			if strings.HasPrefix(each, name) {
+				candidates = append(candidates, command.Name)
+				break
+			}
+		}
+	}
+
+	for _, builtin := range builtins {
+		if strings.HasPrefix(builtin, name) && commandByName(commands, builtin) == nil {
+			candidates = append(candidates, builtin)
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		return name, nil
+	case 1:
+		return candidates[0], nil
+	}
+
+	sort.Strings(candidates)
+	return "", &AmbiguousCommandError{Name: name, Candidates: candidates}
+}
+
+// commandPath resolves a sequence of command names, like
+// "remote add", into the chain of nested commands. It returns
+// nil if there is no such command.
+func (a *Application) commandPath(names []string) ([]*Command, error) {
+	var path []*Command
+
+	commands := a.Commands
+	for _, name := range names {
+		name, err := a.resolveCommand(commands, name)
+		if err != nil {
+			return nil, err
+		}
+
+		command := commandByName(commands, name)
+		if command == nil {
+			return nil, nil
+		}
+
+		path = append(path, command)
+		commands = command.Commands
+	}
+
+	return path, nil
+}
+
 func (a *Application) topicByName(name string) *Topic {
-	for i, topic := range a.Topics {
+	topics := a.topics()
+	for i, topic := range topics {
 		if topic.Name == name {
-			return &a.Topics[i]
+			return &topics[i]
 		}
 	}
 
@@ -120,6 +345,11 @@ func (a *Application) AddCommand(command Command) {
 	}
 }
 
+// AddFlag adds a global option.
+func (a *Application) AddFlag(newFlag Flag) {
+	a.Flags = append(a.Flags, newFlag)
+}
+
 // AddTopic does literally what its name says.
 func (a *Application) AddTopic(topic Topic) {
 	a.Topics = append(a.Topics, topic)
@@ -127,74 +357,385 @@ func (a *Application) AddTopic(topic Topic) {
 
 // Run executes a CLI.
 //
+// It is a thin wrapper around RunArgs that takes arguments from
+// os.Args and reports the error, if any, to the error output.
 // Take a note, Run panics if len(os.Args) < 1
+//
+// While the command runs, SIGINT and SIGTERM cancel its context
+// and make Run return ExitInterrupted. The second signal, or the
+// end of the grace period, exits the process right away.
 func (a *Application) Run() int {
 	if len(os.Args) < 1 {
 		panic("shell-provided arguments are not present")
 	}
-	arguments := os.Args[1:]
+
+	ctx, interruption := a.notifySignals()
+	defer interruption.stop()
+
+	exitcode, err := a.RunContext(ctx, os.Args[1:])
+	if interruption.interrupted() {
+		exitcode = ExitInterrupted
+		if errors.Is(err, context.Canceled) {
+			err = nil
+		}
+	}
+
+	if err != nil {
+		a.reportError(err)
+	}
+
+	return exitcode
+}
+
+// RunArgs executes a CLI with the arguments given (excluding the
+// program name) and returns the exit code.
+//
+// Unlike Run, it never prints the errors nor exits the process:
+// any failure to dispatch the command line is returned as one of
+// UnknownCommandError, AmbiguousCommandError, UnknownTopicError,
+// FlagError or ArgumentError, and the errors of the handlers are
+// returned as they are, along with the exit code ExitCodeMapper
+// maps them to. The misuse of a command comes wrapped in UsageError,
+// so use errors.As to tell FlagError and ArgumentError apart.
+func (a *Application) RunArgs(arguments []string) (int, error) {
+	return a.RunContext(context.Background(), arguments)
+}
+
+// RunContext is RunArgs, passing the context given on to the
+// handlers, see Context.Context.
+func (a *Application) RunContext(ctx context.Context, arguments []string) (int, error) {
+	return a.runContext(ctx, nil, arguments)
+}
+
+// runContext is RunContext, preceding the arguments with the
+// global options given, which the response files are expanded
+// in already, e.g. the ones of the shell and exec lines.
+func (a *Application) runContext(ctx context.Context, options, arguments []string) (exitcode int, err error) {
+	line := append(options[:len(options):len(options)], arguments...)
+
+	if a.recovering() {
+		defer func() {
+			if value := recover(); value != nil {
+				err = a.crash(&PanicError{Value: value, Stack: debug.Stack()}, line)
+				exitcode = a.exitCode(err)
+			}
+		}()
+	}
+
+	exitcode, err = a.runArgs(ctx, options, arguments)
+
+	// The panics of the handlers and hooks are recovered on the way,
+	// but the crash report is written here, along with the arguments.
+	var crash *PanicError
+	if errors.As(err, &crash) && crash.Report == "" && crash.Stack != nil {
+		a.crash(crash, line)
+	}
+
+	if err != nil {
+		exitcode = a.exitCode(err)
+	}
+
+	return exitcode, err
+}
+
+func (a *Application) runArgs(parent context.Context, options, arguments []string) (int, error) {
+	// $ program __complete remote add ""
+	//           ^ completion scripts ask for candidates
+	if len(options) == 0 && len(arguments) > 0 && arguments[0] == "__complete" {
+		a.printCandidates(a.Complete(arguments[1:]))
+		return 0, nil
+	}
+
+	// $ program build @files.txt
+	//                 ^ arguments are read from the file
+	arguments, err := a.expandResponseFiles(arguments, 0)
+	if err != nil {
+		return 1, err
+	}
+
+	// $ program --verbose remote add
+	//           ^ global options may precede the command
+	arguments = append(options[:len(options):len(options)], arguments...)
+	options, arguments, err = a.leadingFlags(a.globalFlags(nil), arguments)
+	if err != nil {
+		return 1, err
+	}
+
+	// $ program -v -- remote
+	//              ^ there are no arguments before the command
+	if len(arguments) > 0 && arguments[0] == "--" {
+		return 1, &FlagError{Name: "-", Reason: "can't precede the command"}
+	}
+
 	// $ program
 	//           ^ no args
 	if len(arguments) == 0 {
 		if a.Default == nil {
+			// $ program -v
+			//           ^ global options are checked, as help does
+			if err := a.parseBuiltin(options); err != nil {
+				return 1, err
+			}
+
 			a.println(a.globalHelp())
-			return 0
+			return 0, nil
+		}
+
+		context, err := a.parseContext(a.globalFlags(nil), options, nil)
+		if err != nil {
+			return 1, err
 		}
 
-		return a.Default(*newContext(a))
+		defer bindContext(parent, context, nil)()
+		return a.execute(nil, context, func(ctx Context) (int, error) {
+			return a.Default(ctx), nil
+		})
 	}
 
-	yankeeGoHome := func(errMsg string) {
-		a.printerr(errMsg)
-		os.Exit(1)
+	subcommandName, err := a.resolveCommand(a.Commands, arguments[0], a.builtins()...)
+	if err != nil {
+		return 1, err
 	}
 
-	subcommandName := arguments[0]
 	subcommand := a.commandByName(subcommandName)
 
 	if subcommandName == "help" {
+		// $ program -v help
+		//           ^ global options are checked, though help ignores them
+		if err := a.parseBuiltin(options); err != nil {
+			return 1, err
+		}
+
 		// $ program help
 		//           ^ one argument
 		if len(arguments) <= 1 {
 			a.println(a.globalHelp())
-			return 0
+			return 0, nil
+		}
+
+		path, err := a.commandPath(arguments[1:])
+		if err != nil {
+			return 1, err
 		}
 
-		command := a.commandByName(arguments[1])
-		if command != nil {
-			a.println(a.commandHelp(command))
-			return 0
+		if path != nil {
+			a.println(a.commandHelp(path))
+			return 0, nil
 		}
 
 		topic := a.topicByName(arguments[1])
-		if topic != nil {
+		if topic != nil && len(arguments) == 2 {
 			a.println(topic.Text)
-			return 0
+			return 0, nil
 		}
 
-		yankeeGoHome("no such command or help topic")
+		return 1, a.unknownTopic(arguments[1:])
 	}
 
 	if subcommandName == "version" {
 		if subcommand != nil {
-			return subcommand.Run(Context{})
+			context, err := a.parseContext(a.globalFlags(nil), options, nil)
+			if err != nil {
+				return 1, err
+			}
+
+			defer bindContext(parent, context, subcommand)()
+			return a.execute([]*Command{subcommand}, context, subcommand.run)
+		}
+
+		if err := a.parseBuiltin(options); err != nil {
+			return 1, err
 		}
 
 		a.printf("%s version %s\n", a.Name, a.Version)
-		return 0
+		return 0, nil
+	}
+
+	if subcommandName == shellCommandName && subcommand == nil && a.Shell != nil {
+		// $ program shell
+		//           ^ interactive session
+		if len(arguments) > 1 {
+			return 1, &ArgumentError{"", "shell takes no arguments"}
+		}
+
+		if err := a.parseBuiltin(options); err != nil {
+			return 1, err
+		}
+
+		return a.runShell(parent, options), nil
+	}
+
+	if subcommandName == execCommandName && subcommand == nil && a.Scripts {
+		// $ program --verbose exec --keep-going steps.txt
+		//           ^ options of every line of the script
+		if err := a.parseBuiltin(options); err != nil {
+			return 1, err
+		}
+
+		return a.runExec(parent, options, arguments[1:])
+	}
+
+	if subcommandName == "completion" && subcommand == nil {
+		if err := a.parseBuiltin(options); err != nil {
+			return 1, err
+		}
+
+		// $ program completion bash
+		//                      ^ shell name
+		if err := checkArguments([]Argument{{Name: "shell"}}, arguments[1:]); err != nil {
+			return 1, err
+		}
+
+		if _, ok := completionScripts[arguments[1]]; !ok {
+			reason := fmt.Sprintf("has unsupported value %q", arguments[1])
+			return 1, &ArgumentError{"shell", reason}
+		}
+
+		return 0, a.Completion(a.stdout(), arguments[1])
 	}
 
 	if subcommand != nil {
-		context, err := a.parseContext(subcommand.Flags, arguments[1:])
-		if err != nil {
-			yankeeGoHome(err.Error())
+		// $ program remote -v add origin
+		//           ^ walking down to the innermost command,
+		//             collecting the inherited options on the way
+		path, argv := []*Command{subcommand}, arguments[1:]
+		for {
+			owner := path[len(path)-1]
+			inherited, rest, err := a.leadingFlags(a.globalFlags(path), argv)
+
+			// $ program remote --all add
+			//                  ^ only the inherited options may
+			//                    precede the subcommand
+			if !owner.handled() && (err != nil || len(rest) > 0 && rest[0] == "--") {
+				if misplaced := a.misplacedOption(path, argv, err); misplaced != nil {
+					return 1, misplaced
+				}
+			}
+
+			if err != nil || len(rest) == 0 {
+				break
+			}
+
+			name, err := a.resolveCommand(owner.Commands, rest[0])
+			if err != nil && !owner.handled() {
+				return 1, err
+			}
+
+			child := owner.commandByName(name)
+			if child == nil {
+				break
+			}
+
+			options = append(options, inherited...)
+			path, argv = append(path, child), rest[1:]
 		}
 
-		return subcommand.Run(*context)
+		return a.runCommand(parent, path, append(options, argv...))
+	}
+
+	return 1, a.unknownCommand(subcommandName, "", a.Commands)
+}
+
+// misplacedOption tells why the walk down from the innermost command
+// of the path stopped short of the subcommand named in argv: either
+// -- or an option of the command, the error given being the one it
+// stopped at, precedes the subcommand. It returns nil if there is
+// no such subcommand.
+func (a *Application) misplacedOption(path []*Command, argv []string, stopped error) error {
+	_, rest, err := a.leadingFlags(a.commandFlags(path), argv)
+	if err != nil || len(rest) == 0 {
+		return nil
+	}
+
+	if rest[0] == "--" {
+		rest = rest[1:]
+	}
+
+	if len(rest) == 0 {
+		return nil
+	}
+
+	commands := path[len(path)-1].Commands
+
+	name, err := a.resolveCommand(commands, rest[0])
+	if err != nil || commandByName(commands, name) == nil {
+		return nil
+	}
+
+	reason := "can't precede the subcommand " + name
+
+	var flag *FlagError
+	if errors.As(stopped, &flag) {
+		return &FlagError{Name: flag.Name, Reason: reason}
+	}
+
+	return &FlagError{Name: "-", Reason: reason}
+}
+
+// runCommand parses the arguments of the innermost command in
+// the path and executes it.
+func (a *Application) runCommand(parent context.Context, path []*Command, argv []string) (int, error) {
+	command := path[len(path)-1]
+
+	context, err := a.parseCommand(path, argv)
+	if err != nil {
+		// $ program remote add --unknown
+		//                      ^ misuse is reported with the usage
+		var flag *FlagError
+		var argument *ArgumentError
+		if errors.As(err, &flag) || errors.As(err, &argument) {
+			err = &UsageError{Err: err, Usage: commandUsage(commandName(path), *command)}
+		}
+
+		return 1, err
+	}
+
+	if !command.handled() && len(command.Commands) > 0 {
+		if len(context.Args) > 0 {
+			return 1, a.unknownCommand(context.Args[0], commandName(path), command.Commands)
+		}
+
+		a.println(a.commandHelp(path))
+		return 0, nil
+	}
+
+	defer bindContext(parent, context, command)()
+	exitcode, err := a.execute(path, context, command.run)
+
+	var usage *UsageError
+	if errors.As(err, &usage) && usage.Usage == "" {
+		usage.Usage = commandUsage(commandName(path), *command)
+	}
+
+	return exitcode, err
+}
+
+// commandFlags returns all the flags the innermost command of
+// the path accepts: its own, inherited ones and global ones.
+func (a *Application) commandFlags(path []*Command) []Flag {
+	flags := append([]Flag{}, path[len(path)-1].flags()...)
+	return append(flags, a.globalFlags(path[:len(path)-1])...)
+}
+
+// globalFlags returns the flags subcommands of the innermost command
+// of the path inherit: ones marked inherited along the path, from the
+// innermost command outwards, followed by the global ones.
+func (a *Application) globalFlags(path []*Command) []Flag {
+	var flags []Flag
+	for i := len(path) - 1; i >= 0; i-- {
+		for _, flag := range path[i].Flags {
+			if flag.Inherited {
+				flags = append(flags, flag)
+			}
+		}
+	}
+
+	flags = append(flags, a.Flags...)
+	if a.Config != nil && flagByName(&a.Flags, configFlagName) == nil {
+		flags = append(flags, configFlag())
 	}
 
-	yankeeGoHome("unknown subcommand \"" + subcommandName + "\"\n")
-	return 1
+	return flags
 }
 
 // Log prints the message to stderrr (each argument takes a distinct line).
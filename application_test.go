@@ -1,7 +1,12 @@
 package climax
 
 import (
+	"bytes"
+	"errors"
+	"io"
 	"os"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -16,7 +21,7 @@ func TestRun_Bare(t *testing.T) {
 }
 
 func TestRun_Version(t *testing.T) {
-	a := New("application")
+	a := newTestApp("application")
 	a.Version = "5.0"
 	setArguments("version")
 	defer setArguments()
@@ -78,7 +83,7 @@ Examples:
 `
 
 func TestRun_Help(t *testing.T) {
-	a := New("application")
+	a := newTestApp("application")
 	a.Brief = "application is a thing"
 
 	a.AddCommand(Command{Name: "open", Brief: "opens smth"})
@@ -169,3 +174,537 @@ func TestAddExample(t *testing.T) {
 		t.Error("failed to add example")
 	}
 }
+
+func TestRunArgs_Errors(t *testing.T) {
+	a := newTestApp("application")
+	a.AddCommand(Command{
+		Name:   "open",
+		Flags:  []Flag{{Name: "force"}},
+		Handle: func(Context) int { return 0 },
+	})
+	defer output.Reset()
+
+	exitcode, err := a.RunArgs([]string{"instal"})
+	if _, ok := err.(*UnknownCommandError); !ok || exitcode != 1 {
+		t.Errorf("unknown command: got (%d, %v)", exitcode, err)
+	}
+
+	exitcode, err = a.RunArgs([]string{"help", "nothing"})
+	if _, ok := err.(*UnknownTopicError); !ok || exitcode != 1 {
+		t.Errorf("unknown topic: got (%d, %v)", exitcode, err)
+	}
+
+	exitcode, err = a.RunArgs([]string{"open", "--forse"})
+	var flag *FlagError
+	if !errors.As(err, &flag) || exitcode != 1 {
+		t.Errorf("unknown flag: got (%d, %v)", exitcode, err)
+	}
+
+	var usage *UsageError
+	if !errors.As(err, &usage) || !strings.HasPrefix(usage.Usage, "open") {
+		t.Errorf("unknown flag isn't reported with the usage: %#v", err)
+	}
+
+	if output.Len() != 0 {
+		t.Errorf("RunArgs printed errors: %q", output.String())
+	}
+}
+
+func TestRunArgs_Dispatch(t *testing.T) {
+	a := newTestApp("application")
+
+	var received Context
+	a.AddCommand(Command{
+		Name:  "open",
+		Flags: []Flag{{Name: "force", Short: "f"}},
+		Handle: func(ctx Context) int {
+			received = ctx
+			return 3
+		},
+	})
+
+	exitcode, err := a.RunArgs([]string{"open", "-f", "file"})
+	if err != nil || exitcode != 3 {
+		t.Fatalf("got (%d, %v), expected (3, nil)", exitcode, err)
+	}
+
+	if !received.Is("force") || len(received.Args) != 1 {
+		t.Errorf("handler got unexpected context:\n%s", received)
+	}
+}
+
+func TestRunArgs_Streams(t *testing.T) {
+	var first, second, stderr bytes.Buffer
+
+	a, b := New("first"), New("second")
+	a.Stdout, a.Stderr = &first, &stderr
+	b.Stdout, b.Stderr = &second, &stderr
+	a.Version, b.Version = "1", "2"
+
+	a.AddCommand(Command{
+		Name: "echo",
+		Handle: func(ctx Context) int {
+			io.Copy(ctx.Stdout(), ctx.Stdin())
+			ctx.Log("done")
+			return 0
+		},
+	})
+	a.Stdin = strings.NewReader("hello")
+
+	a.RunArgs([]string{"version"})
+	b.RunArgs([]string{"version"})
+	a.RunArgs([]string{"echo"})
+
+	if first.String() != "first version 1\nhello" {
+		t.Errorf("unexpected output of first app: %q", first.String())
+	}
+
+	if second.String() != "second version 2\n" {
+		t.Errorf("unexpected output of second app: %q", second.String())
+	}
+
+	if stderr.String() != "first: done\n" {
+		t.Errorf("unexpected error output: %q", stderr.String())
+	}
+}
+
+func TestCommandHelp_Defaults(t *testing.T) {
+	a := newTestApp("application")
+	a.AddCommand(Command{
+		Name: "serve",
+		Help: "Serves.",
+		Flags: []Flag{
+			{Name: "port", Type: IntFlag, Default: "8080", Help: "Port to listen on."},
+			{Name: "debug", Type: BoolFlag, Help: "Log verbosely."},
+		},
+	})
+
+	expected := `Usage: serve [--port] [--debug]
+
+Serves.
+
+Available options:
+
+	--port=int
+		Port to listen on. (default 8080)
+	--debug
+		Log verbosely.
+`
+	if help := a.commandHelp([]*Command{&a.Commands[0]}); help != expected {
+		t.Errorf("command help output is different to expected:\n")
+		t.Logf("- expected:\n%q", expected)
+		t.Logf("- recieved:\n%q", help)
+	}
+}
+
+func TestCommandHelp_Arguments(t *testing.T) {
+	a := newTestApp("application")
+	a.AddCommand(Command{
+		Name: "copy",
+		Help: "Copies files.",
+		Flags: []Flag{
+			{Name: "force", Short: "f", Help: "Overwrite files."},
+			{Name: "mode", Variable: true, Required: true, Help: "File mode."},
+		},
+		Arguments: []Argument{
+			{Name: "target", Help: "Directory to copy to."},
+			{Name: "source", Variadic: true, Optional: true, Help: "Files to copy."},
+		},
+	})
+
+	expected := `Usage: copy [-f] --mode target [source...]
+
+Copies files.
+
+Arguments:
+
+	target
+		Directory to copy to.
+	source
+		Files to copy.
+
+Available options:
+
+	-f, --force
+		Overwrite files.
+	--mode=""
+		File mode.
+`
+	if help := a.commandHelp([]*Command{&a.Commands[0]}); help != expected {
+		t.Errorf("command help output is different to expected:\n")
+		t.Logf("- expected:\n%q", expected)
+		t.Logf("- recieved:\n%q", help)
+	}
+}
+
+const expectedNestedAppHelp string = `Git is a version control system.
+
+Usage:
+
+	git command [arguments]
+
+The commands are:
+
+	init        creates a repository
+	remote      manage remotes
+	remote add  adds a remote
+	remote list lists remotes
+
+Use "git help [command]" for more information about a command.
+
+`
+
+const expectedNestedCommandHelp string = `Usage: remote command [arguments]
+
+Manage the set of tracked repositories.
+
+The subcommands are:
+
+	add         adds a remote
+	list        lists remotes
+
+Use "git help remote [command]" for more information about a command.
+
+`
+
+const expectedSubcommandHelp string = `Usage: remote add name url
+
+Examples:
+
+	$ git remote add origin git://host/repo
+		Tracks origin.
+
+`
+
+func TestRunArgs_Nested(t *testing.T) {
+	var handled string
+	a := newNestedApp(&handled)
+	defer output.Reset()
+
+	if _, err := a.RunArgs([]string{"remote", "add", "origin", "git://x"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if handled != "add origin git://x" {
+		t.Errorf("dispatched to unexpected handler: %q", handled)
+	}
+
+	if _, err := a.RunArgs([]string{"remote", "add", "origin"}); err == nil {
+		t.Errorf("nested command arguments weren't checked")
+	}
+
+	_, err := a.RunArgs([]string{"remote", "ad"})
+	if e, ok := err.(*UnknownCommandError); !ok || e.Name != "remote ad" {
+		t.Errorf("unknown nested command resulted in %v", err)
+	}
+
+	helps := []struct {
+		arguments []string
+		expected  string
+	}{
+		{[]string{"help"}, expectedNestedAppHelp},
+		{[]string{"remote"}, expectedNestedCommandHelp},
+		{[]string{"help", "remote"}, expectedNestedCommandHelp},
+		{[]string{"help", "remote", "add"}, expectedSubcommandHelp},
+	}
+
+	for _, help := range helps {
+		output.Reset()
+		if _, err := a.RunArgs(help.arguments); err != nil {
+			t.Errorf("%q failed: %v", help.arguments, err)
+		}
+
+		if output.String() != help.expected {
+			t.Errorf("%q output is different to expected:\n", help.arguments)
+			t.Logf("- expected:\n%s", help.expected)
+			t.Logf("- recieved:\n%s", output.String())
+		}
+	}
+
+	if _, err := a.RunArgs([]string{"help", "remote", "rename"}); err == nil {
+		t.Errorf("help for unknown nested command didn't fail")
+	}
+}
+
+const expectedGlobalFlagsHelp string = `Tool is a tool.
+
+Usage:
+
+	tool command [arguments]
+
+The commands are:
+
+	remote      manage remotes
+	remote add  adds a remote
+
+Use "tool help [command]" for more information about a command.
+
+Global options:
+
+	-v, --verbose
+		Log verbosely.
+	--config=""
+		Configuration file.
+
+Additional help topics:
+
+	remotes     about remotes
+
+Use "tool help [topic]" for more information about a topic.
+
+`
+
+const expectedInheritedFlagsHelp string = `Usage: remote add [-f]
+
+Available options:
+
+	-f, --fetch
+		Fetch after adding.
+
+Global options:
+
+	-n, --dry-run
+		Change nothing.
+	-v, --verbose
+		Log verbosely.
+	--config=""
+		Configuration file.
+
+`
+
+func TestRunArgs_GlobalFlags(t *testing.T) {
+	var ctx Context
+	a := newTestApp("tool")
+	a.Brief = "Tool is a tool."
+	a.AddFlag(Flag{Name: "verbose", Short: "v", Help: "Log verbosely."})
+	a.AddFlag(Flag{Name: "config", Variable: true, Help: "Configuration file."})
+
+	handle := func(c Context) int {
+		ctx = c
+		return 0
+	}
+
+	remote := Command{
+		Name:  "remote",
+		Brief: "manage remotes",
+		Flags: []Flag{
+			{Name: "dry-run", Short: "n", Inherited: true, Help: "Change nothing."},
+			{Name: "all", Help: "Show all remotes."},
+		},
+		Handle: handle,
+	}
+	remote.AddCommand(Command{
+		Name:   "add",
+		Brief:  "adds a remote",
+		Flags:  []Flag{{Name: "fetch", Short: "f", Help: "Fetch after adding."}},
+		Handle: handle,
+	})
+
+	a.AddCommand(remote)
+	a.AddTopic(Topic{Name: "remotes", Brief: "about remotes"})
+	defer output.Reset()
+
+	lines := [][]string{
+		{"-v", "--config", "x.json", "remote", "-n", "add", "-f", "origin"},
+		{"remote", "add", "origin", "-n", "-f", "--config=x.json", "--verbose"},
+		{"--config=x.json", "-v", "remote", "add", "-nf", "origin"},
+		{"-v", "remote", "-n", "add", "-f", "--config", "x.json", "--", "origin"},
+	}
+
+	for _, line := range lines {
+		ctx = Context{}
+		if _, err := a.RunArgs(line); err != nil {
+			t.Errorf("%q failed: %v", line, err)
+			continue
+		}
+
+		config, _ := ctx.Get("config")
+		if !ctx.Is("verbose") || !ctx.Is("dry-run") || !ctx.Is("fetch") || config != "x.json" {
+			t.Errorf("%q resulted in unexpected context:\n%s", line, ctx)
+		}
+
+		if !reflect.DeepEqual(ctx.Args, []string{"origin"}) {
+			t.Errorf("%q resulted in unexpected arguments: %q", line, ctx.Args)
+		}
+	}
+
+	if _, err := a.RunArgs([]string{"remote", "add", "--all"}); err == nil {
+		t.Errorf("non-inherited flag was accepted by a subcommand")
+	}
+
+	if _, err := a.RunArgs([]string{"--dry-run", "remote"}); err == nil {
+		t.Errorf("inherited flag was accepted before its command")
+	}
+
+	for _, line := range [][]string{{"-v", "--", "remote"}, {"--", "remote"}} {
+		_, err := a.RunArgs(line)
+		if e, ok := err.(*FlagError); !ok || e.Error() != "option -- can't precede the command" {
+			t.Errorf("%q resulted in %v", line, err)
+		}
+	}
+
+	helps := []struct {
+		arguments []string
+		expected  string
+	}{
+		{[]string{"-v", "help"}, expectedGlobalFlagsHelp},
+		{[]string{"help", "remote", "add"}, expectedInheritedFlagsHelp},
+	}
+
+	for _, help := range helps {
+		output.Reset()
+		if _, err := a.RunArgs(help.arguments); err != nil {
+			t.Errorf("%q failed: %v", help.arguments, err)
+		}
+
+		if output.String() != help.expected {
+			t.Errorf("%q output is different to expected:\n", help.arguments)
+			t.Logf("- expected:\n%s", help.expected)
+			t.Logf("- recieved:\n%s", output.String())
+		}
+	}
+}
+
+func TestRunArgs_NestedOptions(t *testing.T) {
+	var handled string
+	a := newNestedApp(&handled)
+	a.Commands[1].Flags = []Flag{{Name: "all", Help: "Show all remotes."}}
+	defer output.Reset()
+
+	misplaced := map[string]string{
+		"remote --all add":    "option -all can't precede the subcommand add",
+		"remote -- add":       "option -- can't precede the subcommand add",
+		"remote --all -- add": "option -all can't precede the subcommand add",
+	}
+
+	for line, message := range misplaced {
+		if _, err := a.RunArgs(strings.Fields(line)); err == nil || err.Error() != message {
+			t.Errorf("%q resulted in %v, expected %q", line, err, message)
+		}
+	}
+}
+
+func TestRunArgs_BuiltinOptions(t *testing.T) {
+	a := newTestApp("tool")
+	a.Version = "1.0"
+	a.Shell = &Shell{}
+	a.Scripts = true
+	a.AddFlag(Flag{Name: "verbose", Short: "v"})
+	a.AddFlag(Flag{Name: "level", Type: IntFlag})
+	a.AddFlag(Flag{Name: "token", Variable: true, Required: true})
+	a.AddCommand(Command{Name: "run", Handle: func(ctx Context) int { return 0 }})
+	defer output.Reset()
+
+	builtins := [][]string{{}, {"help"}, {"version"}, {"completion", "bash"}, {"shell"}, {"exec"}}
+	for _, builtin := range builtins {
+		a.Stdin = strings.NewReader("")
+		if _, err := a.RunArgs(append([]string{"-v"}, builtin...)); err != nil {
+			t.Errorf("%q with a global option failed: %v", builtin, err)
+		}
+
+		_, err := a.RunArgs(append([]string{"--level=abc"}, builtin...))
+		if e, ok := err.(*FlagError); !ok || e.Name != "level" {
+			t.Errorf("%q with a malformed global option resulted in %v", builtin, err)
+		}
+	}
+
+	if _, err := a.RunArgs([]string{"--level"}); err == nil {
+		t.Errorf("global option without a value didn't fail")
+	}
+}
+
+func TestCommandHelp_Env(t *testing.T) {
+	a := newTestApp("application")
+	a.EnvPrefix = "APP"
+	a.AddCommand(Command{
+		Name: "join",
+		Flags: []Flag{
+			{Name: "separator", Variable: true, Env: []string{"SEP"}, Help: "Separator."},
+		},
+	})
+
+	expected := `Usage: join [--separator]
+
+Available options:
+
+	--separator=""
+		Separator. (env SEP, APP_SEPARATOR)
+`
+	if help := a.commandHelp([]*Command{&a.Commands[0]}); help != expected {
+		t.Errorf("command help output is different to expected:\n")
+		t.Logf("- expected:\n%q", expected)
+		t.Logf("- recieved:\n%q", help)
+	}
+}
+
+func TestRunArgs_Aliases(t *testing.T) {
+	var handled string
+	a := newNestedApp(&handled)
+	a.Commands[0].Aliases = []string{"new"}
+	a.Commands[1].Commands[1].Aliases = []string{"ls"}
+	defer output.Reset()
+
+	if _, err := a.RunArgs([]string{"remote", "ls"}); err != nil || handled != "list " {
+		t.Errorf("alias dispatched to %q: %v", handled, err)
+	}
+
+	if _, err := a.RunArgs([]string{"new"}); err != nil || handled != "init " {
+		t.Errorf("alias dispatched to %q: %v", handled, err)
+	}
+
+	if a.isNameAvailable("new") {
+		t.Errorf("alias is not reserved")
+	}
+
+	output.Reset()
+	a.RunArgs([]string{"help", "remote"})
+	if !strings.Contains(output.String(), "list        lists remotes (ls)") {
+		t.Errorf("alias is not listed:\n%s", output.String())
+	}
+
+	output.Reset()
+	a.RunArgs([]string{"help", "remote", "ls"})
+	if !strings.Contains(output.String(), "Aliases: ls\n") {
+		t.Errorf("alias is not shown in command help:\n%s", output.String())
+	}
+}
+
+func TestRunArgs_PrefixMatching(t *testing.T) {
+	var handled string
+	a := newNestedApp(&handled)
+	a.AddFlag(Flag{Name: "verbose"})
+	a.AddFlag(Flag{Name: "version"})
+	a.AddFlag(Flag{Name: "quiet"})
+	a.AddCommand(Command{Name: "index", Handle: func(ctx Context) int { return 0 }})
+	defer output.Reset()
+
+	if _, err := a.RunArgs([]string{"rem", "l"}); err == nil {
+		t.Errorf("prefix matched without prefix matching")
+	}
+
+	a.PrefixMatching = true
+
+	if _, err := a.RunArgs([]string{"--q", "rem", "l"}); err != nil || handled != "list " {
+		t.Errorf("prefixes dispatched to %q: %v", handled, err)
+	}
+
+	output.Reset()
+	if _, err := a.RunArgs([]string{"hel", "rem", "ad"}); err != nil || !strings.HasPrefix(output.String(), "Usage: remote add name url") {
+		t.Errorf("help didn't resolve prefixes: %v\n%s", err, output.String())
+	}
+
+	_, err := a.RunArgs([]string{"in"})
+	if e, ok := err.(*AmbiguousCommandError); !ok || !reflect.DeepEqual(e.Candidates, []string{"index", "init"}) {
+		t.Errorf("ambiguous command resulted in %v", err)
+	}
+
+	_, err = a.RunArgs([]string{"init", "--ver"})
+	var flag *FlagError
+	if !errors.As(err, &flag) || !reflect.DeepEqual(flag.Suggestions, []string{"--verbose", "--version"}) {
+		t.Errorf("ambiguous option resulted in %v", err)
+	}
+
+	if _, err := a.RunArgs([]string{"init", "-q"}); err == nil {
+		t.Errorf("short option was matched by prefix")
+	}
+}
@@ -3,14 +3,69 @@ package climax
 import (
 	"bytes"
 	"os"
+	"strings"
 	"testing"
 )
 
 var output bytes.Buffer
 
-func init() {
-	outputDevice = &output
-	errorDevice = &output
+// newTestApp constructs an application that writes to output.
+func newTestApp(name string) *Application {
+	a := New(name)
+	a.Stdout = &output
+	a.Stderr = &output
+	return a
+}
+
+// newNestedApp constructs a git-like application with nested
+// commands, which record the command handled.
+func newNestedApp(handled *string) *Application {
+	a := newTestApp("git")
+	a.Brief = "Git is a version control system."
+
+	handler := func(name string) CmdHandler {
+		return func(ctx Context) int {
+			*handled = name + " " + strings.Join(ctx.Args, " ")
+			return 0
+		}
+	}
+
+	remote := Command{
+		Name:  "remote",
+		Brief: "manage remotes",
+		Help:  "Manage the set of tracked repositories.",
+	}
+	remote.AddCommand(Command{
+		Name:      "add",
+		Brief:     "adds a remote",
+		Arguments: []Argument{{Name: "name"}, {Name: "url"}},
+		Examples: []Example{
+			{Usecase: "origin git://host/repo", Description: "Tracks origin."},
+		},
+		Handle: handler("add"),
+	})
+	remote.AddCommand(Command{
+		Name:   "list",
+		Brief:  "lists remotes",
+		Handle: handler("list"),
+	})
+
+	a.AddCommand(Command{Name: "init", Brief: "creates a repository", Handle: handler("init")})
+	a.AddCommand(remote)
+	return a
+}
+
+// newGitApp is newNestedApp with options, topics and groups, shared
+// by the tests of the generated completion, documentation and specs.
+func newGitApp() *Application {
+	var handled string
+	a := newNestedApp(&handled)
+	a.AddFlag(Flag{Name: "verbose", Short: "v", Help: "Log verbosely."})
+	a.Commands[0].Flags = []Flag{{Name: "bare", Help: "Create a bare repository."}}
+	a.AddTopic(Topic{Name: "workflows", Brief: "how to collaborate"})
+	a.AddGroup("Plumbing")
+	a.AddCommand(Command{Name: "cat-file", Brief: "shows objects", Group: "Plumbing"})
+	return a
 }
 
 func setArguments(args ...string) {
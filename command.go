@@ -1,10 +1,22 @@
 package climax
 
+import (
+	"os"
+	"path/filepath"
+)
+
 // CmdHandler is a handling function type for functions.
 //
 // Returned integer would be used as application exit status.
 type CmdHandler func(Context) int
 
+// CmdErrHandler is an alternative handling function type,
+// reporting failures with errors instead of exit codes.
+//
+// Returned error is reported by the application and mapped to
+// exit status, see ExitError and UsageError.
+type CmdErrHandler func(Context) error
+
 // Command represents a top-level application subcommand.
 type Command struct {
 	// Name is a [A-Za-z_0-9] identifier of up to 11 characters.
@@ -15,6 +27,12 @@ type Command struct {
 	// Examples: build, list, install
 	Name string
 
+	// Aliases are alternative names of the command, usually
+	// the shorter ones. They are listed in help.
+	//
+	// Examples: rm, ls
+	Aliases []string
+
 	// Brief is a short annotation of action command is capable of.
 	//
 	// Climax doesn't provide any limitations on the brief string
@@ -46,11 +64,55 @@ type Command struct {
 	// Handling, I bet it's pretty straight-forward.
 	Handle CmdHandler
 
+	// HandleErr is used instead of Handle if set, it returns
+	// an error rather than an exit code.
+	HandleErr CmdErrHandler
+
 	// Flags are command-line options.
 	Flags []Flag
 
+	// Arguments describe positional arguments of the command.
+	//
+	// If any are given, the command line is checked against them
+	// before the handler runs, so Context.Args are guaranteed to
+	// fit. Without them, arguments are not checked at all.
+	Arguments []Argument
+
 	// Examples are annotated tips on command usage.
 	Examples []Example
+
+	// Commands are nested subcommands, e.g. "add" and "list"
+	// of the "remote" command, which are invoked as
+	// "app remote add" and "app remote list".
+	//
+	// A command with subcommands, but without a handler,
+	// displays its help entry if invoked on its own.
+	Commands []Command
+
+	// Middleware wraps the handler of the command and handlers
+	// of its subcommands, inside the middleware of the parents.
+	Middleware []Middleware
+
+	// Before, After and Finally hooks run around the handler of
+	// the command and handlers of its subcommands.
+	//
+	// Before hooks run from the outermost (the application) to
+	// the innermost command, while After and Finally ones run in
+	// the reverse order, like deferred calls: the After hook of
+	// each one is followed by its Finally hook. The middleware and
+	// the handler run in between.
+	Before  BeforeHook
+	After   AfterHook
+	Finally FinallyHook
+
+	// TimeoutFlag adds the --timeout option, limiting the run
+	// time of the command: its Context.Context() is cancelled
+	// once the time runs out.
+	TimeoutFlag bool
+
+	// Complete suggests positional arguments for shell completion,
+	// e.g. branch names. It's optional.
+	Complete CompletionFunc
 }
 
 // AddFlag does literally what its name says.
@@ -63,9 +125,55 @@ func (c *Command) AddExample(newExample Example) {
 	c.Examples = append(c.Examples, newExample)
 }
 
+// AddCommand adds a nested subcommand.
+func (c *Command) AddCommand(command Command) {
+	c.Commands = append(c.Commands, command)
+}
+
+// names returns the name of the command, followed by its aliases.
+func (c *Command) names() []string {
+	return append([]string{c.Name}, c.Aliases...)
+}
+
+func (c *Command) commandByName(name string) *Command {
+	return commandByName(c.Commands, name)
+}
+
 // Run executes a command handler and returns corresponding exitcode.
+// The error HandleErr returns is reported by the application of the
+// context, or printed to os.Stderr if there is none.
 func (c Command) Run(context Context) int {
-	return c.Handle(context)
+	exitcode, err := c.run(context)
+	if err == nil {
+		return exitcode
+	}
+
+	app := context.app
+	if app == nil {
+		app = &Application{Name: filepath.Base(os.Args[0])}
+	}
+
+	app.reportError(err)
+	return app.exitCode(err)
+}
+
+// run executes either of the handlers, turning the error
+// into the exit code along the way.
+func (c *Command) run(context Context) (int, error) {
+	if c.HandleErr == nil {
+		return c.Handle(context), nil
+	}
+
+	if err := c.HandleErr(context); err != nil {
+		return exitCode(err), err
+	}
+
+	return 0, nil
+}
+
+// handled tells whether the command has a handler of any kind.
+func (c *Command) handled() bool {
+	return c.Handle != nil || c.HandleErr != nil
 }
 
 // Topic is some sort of a concise wiki page.
@@ -124,6 +232,81 @@ type Flag struct {
 	// For instance, --force is a non-variable flag and
 	// --filter="token" is a variable flag.
 	Variable bool
+
+	// Type is a kind of value the flag accepts. Flags of any
+	// type but StringFlag are variable regardless of Variable.
+	//
+	// Use corresponding Context accessors, e.g. GetInt or
+	// GetDuration, to retrieve converted values.
+	Type FlagType
+
+	// Default is a value variable flag takes when it's absent
+	// on the command line.
+	//
+	// Example: 8080
+	Default string
+
+	// Repeatable variable flags can be passed more than once,
+	// collecting all the values given, retrieved by GetAll.
+	//
+	// Example: -I dir1 -I dir2
+	Repeatable bool
+
+	// SplitCommas makes a repeatable flag also split each of its
+	// values by commas, so --tag a,b is the same as --tag a --tag b.
+	SplitCommas bool
+
+	// Required flags must be present on the command line, unless
+	// they have a default value.
+	Required bool
+
+	// Inherited flags of the command are also accepted by all
+	// of its nested subcommands, before or after their names.
+	Inherited bool
+
+	// Env are names of the environment variables the flag takes
+	// its value from when it's absent on the command line. The
+	// first one defined wins. Non-variable flags accept boolean
+	// values like 1 or true.
+	//
+	// Example: SEPARATOR
+	Env []string
+
+	// Complete suggests values of variable flag for shell
+	// completion, e.g. file names. It's optional.
+	Complete CompletionFunc
+}
+
+// Argument is a named positional argument of the command.
+type Argument struct {
+	// Name is a short lowercase noun, displayed in the usage
+	// line and error messages.
+	//
+	// Example: source
+	Name string
+
+	// Help is displayed under the argument name in the
+	// arguments section of the help entry.
+	Help string
+
+	// Optional arguments may be omitted. Since arguments are
+	// positional, only the trailing ones can be optional.
+	Optional bool
+
+	// Variadic argument takes the rest of the arguments, so
+	// it must be the last one. It requires at least one value,
+	// unless it's optional.
+	Variadic bool
+
+	// Min and Max limit the number of values the variadic
+	// argument takes, zero Max meaning no limit at all. An
+	// optional argument takes no values or at least Min.
+	Min, Max int
+}
+
+// isVariable tells whether the flag takes a value.
+func (f *Flag) isVariable() bool {
+	return f.Variable || f.Type != StringFlag
 }
 
 // Example is an annotated use case of the command.
@@ -0,0 +1,573 @@
+package climax
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// completionScripts are generators of completion scripts by shell.
+var completionScripts = map[string]func(name string, nodes []completionNode) string{
+	"bash": bashCompletion,
+	"zsh":  zshCompletion,
+	"fish": fishCompletion,
+}
+
+// Completion writes a tab completion script of the application for
+// the shell given: bash, zsh or fish. Scripts complete command names,
+// help topics after "help" and the flags of each command.
+//
+// The same script is printed by the built-in "completion" command,
+// so users can install it with something like:
+//
+//	$ app completion bash > /etc/bash_completion.d/app
+func (a *Application) Completion(w io.Writer, shell string) error {
+	generate, ok := completionScripts[shell]
+	if !ok {
+		return fmt.Errorf("unsupported shell %q", shell)
+	}
+
+	_, err := io.WriteString(w, generate(a.Name, a.completionNodes()))
+	return err
+}
+
+// completionShells lists the shells Completion supports.
+func completionShells() []string {
+	var shells []string
+	for shell := range completionScripts {
+		shells = append(shells, shell)
+	}
+
+	sort.Strings(shells)
+	return shells
+}
+
+// completionNode is a position in the command tree, at which
+// the command line can be completed.
+type completionNode struct {
+	// Path is a slash-separated list of the names leading to the
+	// node, e.g. "/remote/add", empty for the top level.
+	Path string
+
+	Words []Candidate
+
+	// Dynamic nodes have completion functions, so the script
+	// asks the application itself to complete them.
+	Dynamic bool
+}
+
+// Candidate is a completion candidate with optional description.
+type Candidate struct {
+	Value       string
+	Description string
+}
+
+// CompletionFunc suggests candidates for the word being completed,
+// given the context of the command line parsed so far. The word can
+// be empty; Climax drops candidates that don't start with it.
+type CompletionFunc func(ctx Context, word string) []Candidate
+
+// completionNodes walks the application and lists the positions
+// of the command line that can be completed.
+func (a *Application) completionNodes() []completionNode {
+	nodes := []completionNode{
+		{Path: "", Words: a.rootCandidates()},
+		{Path: "/help", Words: a.helpCandidates()},
+		{Path: "/completion", Words: shellCandidates()},
+	}
+
+	// Aliases lead to the same commands, so their subtrees are
+	// listed under every name of the command.
+	var walk func(path []*Command, prefix string)
+	walk = func(path []*Command, prefix string) {
+		command := path[len(path)-1]
+		nodes = append(nodes, completionNode{
+			Path:    prefix,
+			Words:   a.commandCandidates(path),
+			Dynamic: a.isDynamic(path),
+		}, completionNode{
+			Path:  "/help" + prefix,
+			Words: nameCandidates(command.Commands),
+		})
+
+		for i := range command.Commands {
+			for _, name := range command.Commands[i].names() {
+				walk(append(path[:len(path):len(path)], &command.Commands[i]), prefix+"/"+name)
+			}
+		}
+	}
+
+	for i := range a.Commands {
+		for _, name := range a.Commands[i].names() {
+			walk([]*Command{&a.Commands[i]}, "/"+name)
+		}
+	}
+
+	return nodes
+}
+
+// nameCandidates lists the names and aliases of the commands.
+func nameCandidates(commands []Command) []Candidate {
+	var candidates []Candidate
+	for _, command := range commands {
+		for _, name := range command.names() {
+			candidates = append(candidates, Candidate{name, command.Brief})
+		}
+	}
+
+	return candidates
+}
+
+func (a *Application) rootCandidates() []Candidate {
+	candidates := append(nameCandidates(a.Commands),
+		Candidate{"help", "show help on commands and topics"},
+		Candidate{"version", "print version"},
+		Candidate{"completion", "print shell completion script"},
+	)
+
+	if a.Shell != nil && a.commandByName(shellCommandName) == nil {
+		candidates = append(candidates, Candidate{shellCommandName, "run commands interactively"})
+	}
+
+	if a.Scripts && a.commandByName(execCommandName) == nil {
+		candidates = append(candidates, Candidate{execCommandName, "run commands from a script"})
+	}
+
+	return append(candidates, flagWords(a.globalFlags(nil))...)
+}
+
+func (a *Application) helpCandidates() []Candidate {
+	candidates := nameCandidates(a.Commands)
+	for _, topic := range a.topics() {
+		candidates = append(candidates, Candidate{topic.Name, topic.Brief})
+	}
+
+	return candidates
+}
+
+func shellCandidates() []Candidate {
+	var candidates []Candidate
+	for _, shell := range completionShells() {
+		candidates = append(candidates, Candidate{shell, shell + " completion script"})
+	}
+
+	return candidates
+}
+
+// commandCandidates are the static candidates for the innermost
+// command of the path: its subcommands and flags.
+func (a *Application) commandCandidates(path []*Command) []Candidate {
+	candidates := nameCandidates(path[len(path)-1].Commands)
+	return append(candidates, flagWords(a.commandFlags(path))...)
+}
+
+// isDynamic tells whether the innermost command of the path or
+// any of its flags has a completion function.
+func (a *Application) isDynamic(path []*Command) bool {
+	if path[len(path)-1].Complete != nil {
+		return true
+	}
+
+	for _, flag := range a.commandFlags(path) {
+		if flag.Complete != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Complete returns the candidates for the last of the words given,
+// which are the command line without the program name. The last word
+// is the one being completed, so it is empty right after a space.
+//
+// The same is printed by the hidden "__complete" command, one candidate
+// per line, with the description, if any, separated by a tab. It's what
+// completion scripts call for commands with completion functions.
+func (a *Application) Complete(words []string) []Candidate {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+
+	word, done := words[len(words)-1], words[:len(words)-1]
+
+	options, rest, err := a.leadingFlags(a.globalFlags(nil), done)
+	if err != nil {
+		return nil
+	}
+
+	if len(rest) == 0 {
+		return a.completeWord(nil, options, word, a.rootCandidates())
+	}
+
+	// $ app rem <TAB>
+	//       ^ the commands are resolved the way they're dispatched
+	name, err := a.resolveCommand(a.Commands, rest[0], a.builtins()...)
+	if err != nil {
+		return nil
+	}
+
+	switch {
+	case name == "help" && len(rest) == 1:
+		return filterCandidates(a.helpCandidates(), word)
+	case name == "help":
+		// $ app help remote <TAB>
+		path, err := a.commandPath(rest[1:])
+		if err != nil || path == nil {
+			return nil
+		}
+
+		return filterCandidates(nameCandidates(path[len(path)-1].Commands), word)
+	case name == "completion" && len(rest) == 1 && a.commandByName("completion") == nil:
+		return filterCandidates(shellCandidates(), word)
+	}
+
+	command := a.commandByName(name)
+	if command == nil {
+		return nil
+	}
+
+	path, argv := []*Command{command}, rest[1:]
+	for {
+		inherited, rest, err := a.leadingFlags(a.globalFlags(path), argv)
+		if err != nil || len(rest) == 0 {
+			break
+		}
+
+		name, err := a.resolveCommand(path[len(path)-1].Commands, rest[0])
+		if err != nil {
+			break
+		}
+
+		child := path[len(path)-1].commandByName(name)
+		if child == nil {
+			break
+		}
+
+		options = append(options, inherited...)
+		path, argv = append(path, child), rest[1:]
+	}
+
+	return a.completeWord(path, append(options, argv...), word, a.commandCandidates(path))
+}
+
+// completeWord completes the word following the arguments of the
+// innermost command of the path, static being its candidates.
+func (a *Application) completeWord(path []*Command, argv []string, word string, static []Candidate) []Candidate {
+	var flags []Flag
+	var section []string
+	if path == nil {
+		flags = a.globalFlags(nil)
+	} else {
+		flags = a.commandFlags(path)
+		section = strings.Fields(commandName(path))
+	}
+
+	ctx, err := a.parseContext(optionalFlags(flags), argv, section)
+	if err != nil {
+		ctx = newContext(a)
+	}
+
+	terminated := false
+	for _, argument := range argv {
+		terminated = terminated || argument == "--"
+	}
+
+	// $ app join --separator <TAB>
+	if n := len(argv); n > 0 && !terminated && looksLikeFlag(argv[n-1]) {
+		name, _ := parseFlagSignature(argv[n-1])
+		flag := flagByName(&flags, name)
+		if flag != nil && needsValue(flag, argv[n-1]) {
+			return completeFlag(flag, *ctx, word, "")
+		}
+	}
+
+	// $ app join --separator=<TAB>
+	if !terminated && looksLikeFlag(word) && strings.Contains(word, "=") {
+		name, value := parseFlagSignature(word)
+		flag := flagByName(&flags, name)
+		if flag == nil {
+			return nil
+		}
+
+		return completeFlag(flag, *ctx, value, word[:len(word)-len(value)])
+	}
+
+	if !terminated && strings.HasPrefix(word, "-") {
+		var candidates []Candidate
+		for _, candidate := range static {
+			if strings.HasPrefix(candidate.Value, "-") {
+				candidates = append(candidates, candidate)
+			}
+		}
+
+		return filterCandidates(candidates, word)
+	}
+
+	var candidates []Candidate
+	for _, candidate := range static {
+		if !strings.HasPrefix(candidate.Value, "-") {
+			candidates = append(candidates, candidate)
+		}
+	}
+
+	if path != nil && path[len(path)-1].Complete != nil {
+		candidates = append(candidates, path[len(path)-1].Complete(*ctx, word)...)
+	}
+
+	return filterCandidates(candidates, word)
+}
+
+// completeFlag completes the value of the flag, prefixing the
+// candidates with the prefix given, e.g. --separator=.
+func completeFlag(flag *Flag, ctx Context, value, prefix string) []Candidate {
+	if flag.Complete == nil {
+		return nil
+	}
+
+	candidates := filterCandidates(flag.Complete(ctx, value), value)
+	for i := range candidates {
+		candidates[i].Value = prefix + candidates[i].Value
+	}
+
+	return candidates
+}
+
+func filterCandidates(candidates []Candidate, word string) []Candidate {
+	var filtered []Candidate
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate.Value, word) {
+			filtered = append(filtered, candidate)
+		}
+	}
+
+	return filtered
+}
+
+// printCandidates prints the candidates in the format of the
+// hidden "__complete" command.
+func (a *Application) printCandidates(candidates []Candidate) {
+	for _, candidate := range candidates {
+		if candidate.Description == "" {
+			a.println(candidate.Value)
+		} else {
+			a.println(candidate.Value + "\t" + firstLine(candidate.Description))
+		}
+	}
+}
+
+func flagWords(flags []Flag) []Candidate {
+	var words []Candidate
+	for _, flag := range flags {
+		description := firstLine(flag.Help)
+
+		words = append(words, Candidate{"--" + flag.Name, description})
+		if flag.Short != "" {
+			words = append(words, Candidate{"-" + flag.Short, description})
+		}
+	}
+
+	return words
+}
+
+func firstLine(text string) string {
+	text = strings.TrimSpace(text)
+	if i := strings.IndexByte(text, '\n'); i >= 0 {
+		return text[:i]
+	}
+
+	return text
+}
+
+// completionIdent turns the application name into a valid
+// shell function name.
+func completionIdent(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+
+		return '_'
+	}, name)
+}
+
+// shellQuote single-quotes the string for bash, zsh and fish.
+func shellQuote(s string, fish bool) string {
+	if fish {
+		s = strings.Replace(s, `\`, `\\`, -1)
+		return "'" + strings.Replace(s, "'", `\'`, -1) + "'"
+	}
+
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// pathPatterns is a case pattern matching the paths of the nodes.
+func pathPatterns(nodes []completionNode, separator string) string {
+	var patterns []string
+	for _, node := range nodes {
+		if node.Path != "" {
+			patterns = append(patterns, shellQuote(node.Path, false))
+		}
+	}
+
+	return strings.Join(patterns, separator)
+}
+
+func bashCompletion(name string, nodes []completionNode) string {
+	ident := completionIdent(name)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# bash completion for %s, generated by climax.\n\n", name)
+	fmt.Fprintf(&b, "_%s_completion() {\n", ident)
+	fmt.Fprintf(&b, "\tlocal cur=\"${COMP_WORDS[COMP_CWORD]}\" cmdpath=\"\" words=\"\" dynamic=\"\" i\n\n")
+	fmt.Fprintf(&b, "\tfor ((i = 1; i < COMP_CWORD; i++)); do\n")
+	fmt.Fprintf(&b, "\t\tcase \"$cmdpath/${COMP_WORDS[i]}\" in\n")
+	fmt.Fprintf(&b, "\t\t%s) cmdpath=\"$cmdpath/${COMP_WORDS[i]}\" ;;\n", pathPatterns(nodes, "|"))
+	fmt.Fprintf(&b, "\t\tesac\n")
+	fmt.Fprintf(&b, "\tdone\n\n")
+	fmt.Fprintf(&b, "\tcase \"$cmdpath\" in\n")
+	for _, node := range nodes {
+		var words []string
+		for _, word := range node.Words {
+			words = append(words, word.Value)
+		}
+
+		if node.Dynamic {
+			fmt.Fprintf(&b, "\t%s) dynamic=1 ;;\n", shellQuote(node.Path, false))
+		} else {
+			fmt.Fprintf(&b, "\t%s) words=%s ;;\n", shellQuote(node.Path, false), shellQuote(strings.Join(words, " "), false))
+		}
+	}
+	fmt.Fprintf(&b, "\tesac\n\n")
+	fmt.Fprintf(&b, "\tif [ -n \"$dynamic\" ]; then\n")
+	// COMP_WORDS are split at COMP_WORDBREAKS too, e.g. --flag=value
+	// makes three words, so the words not separated by spaces on the
+	// command line are joined back, and the candidates lose the part
+	// of the joined word before the current one.
+	fmt.Fprintf(&b, "\t\tlocal line=\"${COMP_LINE:0:COMP_POINT}\" trimmed word prefix\n")
+	fmt.Fprintf(&b, "\t\tlocal -a args\n")
+	fmt.Fprintf(&b, "\t\tfor ((i = 0; i <= COMP_CWORD; i++)); do\n")
+	fmt.Fprintf(&b, "\t\t\tword=\"${COMP_WORDS[i]}\"\n")
+	fmt.Fprintf(&b, "\t\t\ttrimmed=\"${line#\"${line%%%%[![:space:]]*}\"}\"\n")
+	fmt.Fprintf(&b, "\t\t\tif ((i > 0)) && [ \"$trimmed\" = \"$line\" ]; then\n")
+	fmt.Fprintf(&b, "\t\t\t\targs[${#args[@]}-1]+=\"$word\"\n")
+	fmt.Fprintf(&b, "\t\t\telse\n")
+	fmt.Fprintf(&b, "\t\t\t\targs+=(\"$word\")\n")
+	fmt.Fprintf(&b, "\t\t\tfi\n")
+	fmt.Fprintf(&b, "\t\t\tline=\"${trimmed#\"$word\"}\"\n")
+	fmt.Fprintf(&b, "\t\tdone\n")
+	fmt.Fprintf(&b, "\t\tprefix=\"${args[${#args[@]}-1]}\"\n")
+	fmt.Fprintf(&b, "\t\tprefix=\"${prefix%%\"$cur\"}\"\n\n")
+	fmt.Fprintf(&b, "\t\tlocal IFS=$'\\n'\n")
+	fmt.Fprintf(&b, "\t\tCOMPREPLY=($(\"${COMP_WORDS[0]}\" __complete \"${args[@]:1}\" 2>/dev/null | cut -f1))\n")
+	fmt.Fprintf(&b, "\t\tCOMPREPLY=(\"${COMPREPLY[@]#\"$prefix\"}\")\n")
+	fmt.Fprintf(&b, "\t\treturn\n")
+	fmt.Fprintf(&b, "\tfi\n\n")
+	fmt.Fprintf(&b, "\tCOMPREPLY=($(compgen -W \"$words\" -- \"$cur\"))\n")
+	fmt.Fprintf(&b, "}\n\n")
+	fmt.Fprintf(&b, "complete -F _%s_completion %s\n", ident, name)
+
+	return b.String()
+}
+
+func zshCompletion(name string, nodes []completionNode) string {
+	ident := completionIdent(name)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "#compdef %s\n", name)
+	fmt.Fprintf(&b, "# zsh completion for %s, generated by climax.\n\n", name)
+	fmt.Fprintf(&b, "_%s() {\n", ident)
+	fmt.Fprintf(&b, "\tlocal cmdpath=\"\" dynamic=\"\" line i\n")
+	fmt.Fprintf(&b, "\tlocal -a candidates\n\n")
+	fmt.Fprintf(&b, "\tfor ((i = 2; i < CURRENT; i++)); do\n")
+	fmt.Fprintf(&b, "\t\tcase \"$cmdpath/${words[i]}\" in\n")
+	fmt.Fprintf(&b, "\t\t%s) cmdpath=\"$cmdpath/${words[i]}\" ;;\n", pathPatterns(nodes, "|"))
+	fmt.Fprintf(&b, "\t\tesac\n")
+	fmt.Fprintf(&b, "\tdone\n\n")
+	fmt.Fprintf(&b, "\tcase \"$cmdpath\" in\n")
+	for _, node := range nodes {
+		var candidates []string
+		for _, word := range node.Words {
+			candidate := strings.Replace(word.Value, ":", `\:`, -1)
+			if word.Description != "" {
+				candidate += ":" + word.Description
+			}
+
+			candidates = append(candidates, shellQuote(candidate, false))
+		}
+
+		if node.Dynamic {
+			fmt.Fprintf(&b, "\t%s) dynamic=1 ;;\n", shellQuote(node.Path, false))
+		} else {
+			fmt.Fprintf(&b, "\t%s) candidates=(%s) ;;\n", shellQuote(node.Path, false), strings.Join(candidates, " "))
+		}
+	}
+	fmt.Fprintf(&b, "\tesac\n\n")
+	fmt.Fprintf(&b, "\tif [[ -n $dynamic ]]; then\n")
+	fmt.Fprintf(&b, "\t\tfor line in ${(f)\"$(${words[1]} __complete \"${(@)words[2,CURRENT]}\" 2>/dev/null)\"}; do\n")
+	fmt.Fprintf(&b, "\t\t\tif [[ $line == *$'\\t'* ]]; then\n")
+	fmt.Fprintf(&b, "\t\t\t\tcandidates+=(\"${${line%%%%$'\\t'*}//:/\\\\:}:${line#*$'\\t'}\")\n")
+	fmt.Fprintf(&b, "\t\t\telse\n")
+	fmt.Fprintf(&b, "\t\t\t\tcandidates+=(\"${line//:/\\\\:}\")\n")
+	fmt.Fprintf(&b, "\t\t\tfi\n")
+	fmt.Fprintf(&b, "\t\tdone\n")
+	fmt.Fprintf(&b, "\tfi\n\n")
+	fmt.Fprintf(&b, "\t_describe -t commands %s candidates\n", shellQuote(name, false))
+	fmt.Fprintf(&b, "}\n\n")
+	fmt.Fprintf(&b, "compdef _%s %s\n", ident, name)
+
+	return b.String()
+}
+
+func fishCompletion(name string, nodes []completionNode) string {
+	ident := completionIdent(name)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# fish completion for %s, generated by climax.\n\n", name)
+	fmt.Fprintf(&b, "function __%s_climax_at\n", ident)
+	fmt.Fprintf(&b, "\tset -l cmdpath \"\"\n")
+	fmt.Fprintf(&b, "\tfor word in (commandline -opc)[2..-1]\n")
+	fmt.Fprintf(&b, "\t\tswitch \"$cmdpath/$word\"\n")
+	fmt.Fprintf(&b, "\t\t\tcase %s\n", pathPatterns(nodes, " "))
+	fmt.Fprintf(&b, "\t\t\t\tset cmdpath \"$cmdpath/$word\"\n")
+	fmt.Fprintf(&b, "\t\tend\n")
+	fmt.Fprintf(&b, "\tend\n")
+	fmt.Fprintf(&b, "\ttest \"x$cmdpath\" = \"x$argv[1]\"\n")
+	fmt.Fprintf(&b, "end\n\n")
+	fmt.Fprintf(&b, "function __%s_climax_complete\n", ident)
+	fmt.Fprintf(&b, "\t%s __complete (commandline -opc)[2..-1] (commandline -ct)\n", name)
+	fmt.Fprintf(&b, "end\n\n")
+	fmt.Fprintf(&b, "complete -c %s -f\n", name)
+	for _, node := range nodes {
+		condition := shellQuote(fmt.Sprintf("__%s_climax_at %s", ident, shellQuote(node.Path, false)), true)
+
+		if node.Dynamic {
+			fmt.Fprintf(&b, "complete -c %s -n %s -a '(__%s_climax_complete)'\n", name, condition, ident)
+			continue
+		}
+
+		for _, word := range node.Words {
+			fmt.Fprintf(&b, "complete -c %s -n %s", name, condition)
+
+			switch {
+			case strings.HasPrefix(word.Value, "--"):
+				fmt.Fprintf(&b, " -l %s", shellQuote(word.Value[2:], true))
+			case strings.HasPrefix(word.Value, "-") && len(word.Value) == 2:
+				fmt.Fprintf(&b, " -s %s", shellQuote(word.Value[1:], true))
+			case strings.HasPrefix(word.Value, "-"):
+				fmt.Fprintf(&b, " -o %s", shellQuote(word.Value[1:], true))
+			default:
+				fmt.Fprintf(&b, " -a %s", shellQuote(word.Value, true))
+			}
+
+			if word.Description != "" {
+				fmt.Fprintf(&b, " -d %s", shellQuote(word.Description, true))
+			}
+
+			fmt.Fprintf(&b, "\n")
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,305 @@
+package climax
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompletion(t *testing.T) {
+	a := newGitApp()
+
+	expected := map[string][]string{
+		"bash": {
+			"complete -F _git_completion git",
+			`'/remote') words='add list --verbose -v' ;;`,
+			`'/help') words='init remote cat-file workflows' ;;`,
+		},
+		"zsh": {
+			"#compdef git",
+			`'/init') candidates=('--bare:Create a bare repository.' '--verbose:Log verbosely.' '-v:Log verbosely.') ;;`,
+			"compdef _git git",
+			`$(${words[1]} __complete "${(@)words[2,CURRENT]}" 2>/dev/null)`,
+		},
+		"fish": {
+			`complete -c git -n '__git_climax_at \'\'' -a 'remote' -d 'manage remotes'`,
+			`complete -c git -n '__git_climax_at \'/init\'' -l 'bare' -d 'Create a bare repository.'`,
+			`complete -c git -n '__git_climax_at \'/remote\'' -s 'v' -d 'Log verbosely.'`,
+		},
+	}
+
+	for shell, lines := range expected {
+		var b bytes.Buffer
+		if err := a.Completion(&b, shell); err != nil {
+			t.Errorf("%s: %v", shell, err)
+			continue
+		}
+
+		for _, line := range lines {
+			if !strings.Contains(b.String(), line) {
+				t.Errorf("%s script lacks %q:\n%s", shell, line, b.String())
+			}
+		}
+	}
+
+	if err := a.Completion(&bytes.Buffer{}, "tcsh"); err == nil {
+		t.Errorf("unsupported shell didn't fail")
+	}
+}
+
+func TestCompletion_Command(t *testing.T) {
+	a := newGitApp()
+	defer output.Reset()
+
+	if _, err := a.RunArgs([]string{"completion", "fish"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(output.String(), "# fish completion for git") {
+		t.Errorf("completion command printed unexpected output:\n%s", output.String())
+	}
+
+	if _, err := a.RunArgs([]string{"completion", "tcsh"}); err == nil {
+		t.Errorf("unsupported shell didn't fail")
+	}
+
+	expected := map[string]string{
+		"completion":            "argument shell is missing",
+		"completion bash extra": `unexpected argument "extra"`,
+	}
+
+	for line, message := range expected {
+		if _, err := a.RunArgs(strings.Fields(line)); err == nil || err.Error() != message {
+			t.Errorf("%q resulted in %v, expected %q", line, err, message)
+		}
+	}
+}
+
+func TestCompletion_Bash(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash is not available")
+	}
+
+	a := newGitApp()
+	a.Commands[0].Flags = append(a.Commands[0].Flags, Flag{
+		Name:     "template",
+		Variable: true,
+		Complete: func(ctx Context, word string) []Candidate { return nil },
+	})
+
+	var script bytes.Buffer
+	a.Completion(&script, "bash")
+
+	// The application is stubbed, answering the completion of the
+	// template option alone.
+	stub := `git() { local IFS=' '; [ "$*" = "__complete init --template=d" ] && printf '%s\t\n' --template=default; }`
+
+	cases := map[string]string{
+		"git ":                   "init remote cat-file help version completion --verbose -v",
+		"git re":                 "remote",
+		"git help w":             "workflows",
+		"git help remote ":       "add list",
+		"git remote ":            "add list --verbose -v",
+		"git -v remote a":        "add",
+		"git remote add -":       "--verbose -v",
+		"git completion z":       "zsh",
+		"git init --template=d":  "default",
+		"git init  --template=d": "default",
+		"git init --template =d": "",
+	}
+
+	for line, expected := range cases {
+		// Bash breaks the words at = as well.
+		var words []string
+		for _, field := range strings.Split(line, " ") {
+			parts := strings.Split(field, "=")
+			for i, part := range parts {
+				if i > 0 {
+					words = append(words, "=")
+				}
+
+				if part != "" || len(parts) == 1 {
+					words = append(words, shellQuote(part, false))
+				}
+			}
+		}
+
+		command := script.String() + stub + fmt.Sprintf(`
+COMP_LINE=%s
+COMP_POINT=%d
+COMP_WORDS=(%s)
+COMP_CWORD=%d
+_git_completion
+echo "${COMPREPLY[*]}"`, shellQuote(line, false), len(line), strings.Join(words, " "), len(words)-1)
+
+		out, err := exec.Command(bash, "-c", command).Output()
+		if err != nil {
+			t.Errorf("%q: %v", line, err)
+			continue
+		}
+
+		if actual := strings.TrimSpace(string(out)); actual != expected {
+			t.Errorf("%q completed to %q, expected %q", line, actual, expected)
+		}
+	}
+}
+
+func TestComplete(t *testing.T) {
+	a := newGitApp()
+
+	add := &a.Commands[1].Commands[0]
+	add.Flags = []Flag{{
+		Name:     "track",
+		Short:    "t",
+		Variable: true,
+		Complete: func(ctx Context, word string) []Candidate {
+			return []Candidate{{"master", "default branch"}, {"main", ""}, {"next", ""}}
+		},
+	}}
+	add.Complete = func(ctx Context, word string) []Candidate {
+		if ctx.Is("verbose") {
+			return []Candidate{{"origin", "verbose"}}
+		}
+
+		return []Candidate{{"origin", ""}, {"upstream", ""}}
+	}
+
+	cases := map[string]string{
+		"":                              "init remote cat-file help version completion",
+		"re":                            "remote",
+		"help w":                        "workflows",
+		"help remote ":                  "add list",
+		"help remote l":                 "list",
+		"help remote add ":              "",
+		"remote a":                      "add",
+		"remote add ":                   "origin upstream",
+		"remote add u":                  "upstream",
+		"-v remote add ":                "origin",
+		"remote add --track ma":         "master main",
+		"remote add -t ":                "master main next",
+		"remote add --track=n":          "--track=next",
+		"remote add --":                 "--track --verbose",
+		"remote add -- --":              "",
+		"remote add origin --track m":   "master main",
+		"completion ":                   "bash fish zsh",
+		"unknown ":                      "",
+		"remote add --unknown=":         "",
+		"--verbose remote add --track ": "master main next",
+	}
+
+	for line, expected := range cases {
+		var values []string
+		for _, candidate := range a.Complete(strings.Split(line, " ")) {
+			values = append(values, candidate.Value)
+		}
+
+		if actual := strings.Join(values, " "); actual != expected {
+			t.Errorf("%q completed to %q, expected %q", line, actual, expected)
+		}
+	}
+}
+
+func TestComplete_Command(t *testing.T) {
+	a := newGitApp()
+	a.Commands[1].Commands[0].Flags = []Flag{{
+		Name:     "track",
+		Variable: true,
+		Complete: func(ctx Context, word string) []Candidate {
+			return []Candidate{{"master", "default branch"}, {"main", ""}}
+		},
+	}}
+	defer output.Reset()
+
+	if _, err := a.RunArgs([]string{"__complete", "remote", "add", "--track", "m"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := "master\tdefault branch\nmain\n"; output.String() != expected {
+		t.Errorf("__complete printed %q, expected %q", output.String(), expected)
+	}
+
+	var script bytes.Buffer
+	a.Completion(&script, "bash")
+	if !strings.Contains(script.String(), "'/remote/add') dynamic=1 ;;") {
+		t.Errorf("bash script doesn't complete dynamic command dynamically:\n%s", script.String())
+	}
+}
+
+func TestComplete_Aliases(t *testing.T) {
+	a := newGitApp()
+	a.Commands[1].Commands[0].Complete = func(ctx Context, word string) []Candidate {
+		return []Candidate{{"origin", ""}, {"upstream", ""}}
+	}
+	a.Commands[1].Aliases = []string{"rmt"}
+	a.Commands[1].Commands[1].Aliases = []string{"ls"}
+
+	cases := map[string]string{
+		"rm":          "rmt",
+		"help rm":     "rmt",
+		"rmt ":        "add list ls",
+		"rmt l":       "list ls",
+		"rmt add ":    "origin upstream",
+		"remote ls -": "--verbose -v",
+	}
+
+	for line, expected := range cases {
+		var values []string
+		for _, candidate := range a.Complete(strings.Split(line, " ")) {
+			values = append(values, candidate.Value)
+		}
+
+		if actual := strings.Join(values, " "); actual != expected {
+			t.Errorf("%q completed to %q, expected %q", line, actual, expected)
+		}
+	}
+
+	var script bytes.Buffer
+	a.Completion(&script, "bash")
+	for _, fragment := range []string{
+		"'/rmt') words='add list ls --verbose -v' ;;",
+		"'/rmt/add') dynamic=1 ;;",
+		"'/remote/ls') words='--verbose -v' ;;",
+	} {
+		if !strings.Contains(script.String(), fragment) {
+			t.Errorf("bash script lacks %q:\n%s", fragment, script.String())
+		}
+	}
+
+	_, err := a.RunArgs([]string{"rmtt"})
+	if e, ok := err.(*UnknownCommandError); !ok || !reflect.DeepEqual(e.Suggestions, []string{"rmt"}) {
+		t.Errorf("misspelled alias resulted in %#v", err)
+	}
+}
+
+func TestComplete_PrefixMatching(t *testing.T) {
+	a := newGitApp()
+	a.PrefixMatching = true
+	a.Commands[1].Commands[0].Complete = func(ctx Context, word string) []Candidate {
+		return []Candidate{{"origin", ""}}
+	}
+
+	cases := map[string]string{
+		"rem ":        "add list",
+		"rem a":       "add",
+		"rem ad ":     "origin",
+		"comp ":       "bash fish zsh",
+		"help rem l":  "list",
+		"c ":          "",
+		"-v rem ad o": "origin",
+	}
+
+	for line, expected := range cases {
+		var values []string
+		for _, candidate := range a.Complete(strings.Split(line, " ")) {
+			values = append(values, candidate.Value)
+		}
+
+		if actual := strings.Join(values, " "); actual != expected {
+			t.Errorf("%q completed to %q, expected %q", line, actual, expected)
+		}
+	}
+}
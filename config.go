@@ -0,0 +1,293 @@
+package climax
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Config describes the configuration file of the application.
+//
+// Configuration values take precedence over flag defaults, but
+// not over environment variables nor the command line. Global
+// flags are keyed by their names at the top level of the file,
+// while the flags of the commands are nested under command names:
+//
+//	{
+//	    "verbose": true,
+//	    "join": {"separator": "."},
+//	    "remote": {"add": {"fetch": true}}
+//	}
+//
+// Nested commands also see the values of the outer ones, so a
+// value for an inherited flag can be put next to its command.
+type Config struct {
+	// Path is the default location of the configuration file.
+	// The leading ~ stands for the home directory of the user.
+	//
+	// The file is optional, unless the user points to it with
+	// the --config option.
+	//
+	// Example: ~/.config/camus/config.json
+	Path string
+
+	// Format decodes the file, JSON is used if it's nil.
+	Format ConfigFormat
+}
+
+// ConfigFormat decodes configuration files of particular format
+// into a tree of values, like encoding/json does: nested objects
+// are map[string]interface{} and lists are []interface{}.
+type ConfigFormat interface {
+	Decode(data []byte) (map[string]interface{}, error)
+}
+
+// JSONConfig is a built-in JSON configuration format.
+type JSONConfig struct{}
+
+// Decode decodes a JSON object.
+func (JSONConfig) Decode(data []byte) (map[string]interface{}, error) {
+	var tree map[string]interface{}
+	err := json.Unmarshal(data, &tree)
+	return tree, err
+}
+
+// ConfigError is returned when the configuration file can't
+// be read or decoded.
+type ConfigError struct {
+	Path string
+	Err  error
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("config %s: %s", e.Path, e.Err)
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+const configFlagName = "config"
+
+// configFlag is the global option selecting the configuration file.
+func configFlag() Flag {
+	return Flag{
+		Name:     configFlagName,
+		Usage:    `--config="file"`,
+		Help:     "Read configuration from the file given.",
+		Variable: true,
+	}
+}
+
+const configTopicName = "config"
+
+// configTopic generates a topic documenting the configuration keys.
+func (a *Application) configTopic() Topic {
+	text := "The configuration file is read from " + a.Config.Path + ",\n"
+	text += "unless the --config option tells otherwise. Its values take\n"
+	text += "precedence over flag defaults, but not over environment\n"
+	text += "variables nor the command line.\n\n"
+	text += "Dots in keys stand for nested objects, e.g. \"remote.add.fetch\"\n"
+	text += "is the \"fetch\" key of the \"add\" object in the \"remote\" one.\n\n"
+	text += "Keys:\n"
+
+	var keys []Flag
+	for _, flag := range a.Flags {
+		if flag.Name != configFlagName {
+			keys = append(keys, flag)
+		}
+	}
+
+	var walk func(prefix string, commands []Command)
+	walk = func(prefix string, commands []Command) {
+		for _, command := range commands {
+			for _, flag := range command.Flags {
+				flag.Name = prefix + command.Name + "." + flag.Name
+				keys = append(keys, flag)
+			}
+
+			walk(prefix+command.Name+".", command.Commands)
+		}
+	}
+	walk("", a.Commands)
+
+	for _, key := range keys {
+		text += "\n\t" + key.Name
+		if key.Help != "" {
+			text += "\n\t\t" + alignMultilineHelp(key.Help)
+		}
+	}
+
+	return Topic{
+		Name:  configTopicName,
+		Brief: "configuration file keys",
+		Text:  text,
+	}
+}
+
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+
+	return filepath.Join(home, path[1:])
+}
+
+// loadConfig reads the configuration file, selected by the
+// --config option or the default one. It returns nil if the
+// default file doesn't exist.
+func (a *Application) loadConfig(ctx *Context) (map[string]interface{}, error) {
+	path, explicit := ctx.Variable[configFlagName]
+	if !explicit {
+		path = a.Config.Path
+	}
+
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := ioutil.ReadFile(expandHome(path))
+	if os.IsNotExist(err) && !explicit {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, &ConfigError{path, err}
+	}
+
+	format := a.Config.Format
+	if format == nil {
+		format = JSONConfig{}
+	}
+
+	tree, err := format.Decode(data)
+	if err != nil {
+		return nil, &ConfigError{path, err}
+	}
+
+	return tree, nil
+}
+
+// configValue looks the flag up in the section of the tree and
+// then in the outer ones.
+func configValue(tree map[string]interface{}, section []string, flagName string) (interface{}, bool) {
+	sections := []map[string]interface{}{tree}
+	for _, name := range section {
+		nested, ok := sections[len(sections)-1][name].(map[string]interface{})
+		if !ok {
+			break
+		}
+
+		sections = append(sections, nested)
+	}
+
+	for i := len(sections) - 1; i >= 0; i-- {
+		if value, ok := sections[i][flagName]; ok {
+			if _, isSection := value.(map[string]interface{}); !isSection {
+				return value, true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// configStrings converts a decoded configuration value to strings.
+func configStrings(value interface{}) ([]string, error) {
+	switch value := value.(type) {
+	case string:
+		return []string{value}, nil
+	case bool:
+		return []string{strconv.FormatBool(value)}, nil
+	case float64:
+		return []string{strconv.FormatFloat(value, 'f', -1, 64)}, nil
+	case int:
+		return []string{strconv.Itoa(value)}, nil
+	case int64:
+		return []string{strconv.FormatInt(value, 10)}, nil
+	case []interface{}:
+		var values []string
+		for _, each := range value {
+			converted, err := configStrings(each)
+			if err != nil || len(converted) != 1 {
+				return nil, fmt.Errorf("has invalid list value %v", value)
+			}
+
+			values = append(values, converted[0])
+		}
+
+		return values, nil
+	}
+
+	return nil, fmt.Errorf("has unsupported value %v", value)
+}
+
+// setFromConfig sets the flag from the configuration tree.
+func (c *Context) setFromConfig(flag *Flag, tree map[string]interface{}, section []string) error {
+	value, ok := configValue(tree, section, flag.Name)
+	if !ok {
+		return nil
+	}
+
+	values, err := configStrings(value)
+	if err == nil && len(values) > 1 && !flag.Repeatable {
+		err = fmt.Errorf("takes a single value")
+	}
+
+	if err != nil {
+		return &FlagError{Name: flag.Name, Reason: "from config " + err.Error()}
+	}
+
+	for _, each := range values {
+		if flag.isVariable() {
+			err = c.setValue(flag, each)
+		} else {
+			var set bool
+			set, err = parseBool(each)
+			if set {
+				c.NonVariable[flag.Name] = true
+			}
+		}
+
+		if err != nil {
+			return &FlagError{Name: flag.Name, Reason: "from config " + err.Error()}
+		}
+	}
+
+	if c.Is(flag.Name) {
+		c.setSource(flag.Name, SourceConfig)
+	}
+
+	return nil
+}
+
+// topics returns the topics of the application, including the
+// generated ones, unless a topic or command of the same name
+// would hide them.
+func (a *Application) topics() []Topic {
+	if a.Config == nil || a.hasTopic(configTopicName) || a.commandByName(configTopicName) != nil {
+		return a.Topics
+	}
+
+	topics := append([]Topic{}, a.Topics...)
+	return append(topics, a.configTopic())
+}
+
+func (a *Application) hasTopic(name string) bool {
+	for _, topic := range a.Topics {
+		if topic.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
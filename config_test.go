@@ -0,0 +1,212 @@
+package climax
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testConfig string = `{
+	"verbose": true,
+	"level": 2,
+	"join": {
+		"separator": ".",
+		"limit": "1k",
+		"tags": ["a", "b"]
+	},
+	"remote": {
+		"dry-run": true,
+		"add": {"name": "origin"}
+	}
+}`
+
+func newConfigApp(t *testing.T, received *Context) *Application {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := ioutil.WriteFile(path, []byte(testConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	handle := func(ctx Context) int {
+		*received = ctx
+		return 0
+	}
+
+	a := newTestApp("app")
+	a.Config = &Config{Path: path}
+	a.AddFlag(Flag{Name: "verbose", Help: "Log verbosely."})
+	a.AddFlag(Flag{Name: "level", Type: IntFlag, Default: "1"})
+
+	a.AddCommand(Command{
+		Name: "join",
+		Flags: []Flag{
+			{Name: "separator", Variable: true, Env: []string{"CLIMAX_TEST_SEPARATOR"}},
+			{Name: "limit", Type: SizeFlag, Default: "10"},
+			{Name: "tags", Variable: true, Repeatable: true},
+			{Name: "prefix", Variable: true, Default: ">"},
+		},
+		Handle: handle,
+	})
+
+	remote := Command{
+		Name:  "remote",
+		Flags: []Flag{{Name: "dry-run", Inherited: true}},
+	}
+	remote.AddCommand(Command{
+		Name:   "add",
+		Flags:  []Flag{{Name: "name", Variable: true, Required: true, Help: "Remote name."}},
+		Handle: handle,
+	})
+	a.AddCommand(remote)
+
+	return a
+}
+
+func TestConfig(t *testing.T) {
+	var ctx Context
+	a := newConfigApp(t, &ctx)
+	defer output.Reset()
+
+	os.Setenv("CLIMAX_TEST_SEPARATOR", "-")
+	defer os.Unsetenv("CLIMAX_TEST_SEPARATOR")
+
+	if _, err := a.RunArgs([]string{"join", "--level=3"}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]struct {
+		value  string
+		source Source
+	}{
+		"verbose":   {"", SourceConfig},
+		"level":     {"3", SourceCommandLine},
+		"separator": {"-", SourceEnvironment},
+		"limit":     {"1k", SourceConfig},
+		"tags":      {"b", SourceConfig},
+		"prefix":    {">", SourceDefault},
+	}
+
+	for name, flag := range expected {
+		value, _ := ctx.Get(name)
+		if value != flag.value || ctx.Source(name) != flag.source {
+			t.Errorf("flag %s = %q from %s, expected %q from %s",
+				name, value, ctx.Source(name), flag.value, flag.source)
+		}
+	}
+
+	if tags := ctx.GetAll("tags"); !reflect.DeepEqual(tags, []string{"a", "b"}) {
+		t.Errorf("list value resulted in %q", tags)
+	}
+
+	if _, err := a.RunArgs([]string{"remote", "add"}); err != nil {
+		t.Fatalf("required flag wasn't satisfied by config: %v", err)
+	}
+
+	if name, _ := ctx.Get("name"); name != "origin" || !ctx.Is("dry-run") {
+		t.Errorf("nested config sections didn't apply:\n%s", ctx)
+	}
+}
+
+func TestConfig_EnvFalse(t *testing.T) {
+	var ctx Context
+	a := newConfigApp(t, &ctx)
+	a.EnvPrefix = "CLIMAX_TEST"
+	defer output.Reset()
+
+	os.Setenv("CLIMAX_TEST_VERBOSE", "0")
+	defer os.Unsetenv("CLIMAX_TEST_VERBOSE")
+
+	if _, err := a.RunArgs([]string{"join"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if ctx.Is("verbose") || ctx.Source("verbose") != SourceEnvironment {
+		t.Errorf("config overrode the environment: verbose from %s", ctx.Source("verbose"))
+	}
+
+	if _, err := a.RunArgs([]string{"join", "--verbose"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !ctx.Is("verbose") || ctx.Source("verbose") != SourceCommandLine {
+		t.Errorf("environment overrode the command line: verbose from %s", ctx.Source("verbose"))
+	}
+}
+
+func TestConfig_Flag(t *testing.T) {
+	var ctx Context
+	a := newConfigApp(t, &ctx)
+	defer output.Reset()
+
+	other := filepath.Join(t.TempDir(), "other.json")
+	ioutil.WriteFile(other, []byte(`{"join": {"separator": ","}}`), 0644)
+
+	if _, err := a.RunArgs([]string{"--config", other, "join"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if value, _ := ctx.Get("separator"); value != "," || ctx.Is("verbose") {
+		t.Errorf("--config didn't select the file:\n%s", ctx)
+	}
+
+	missing := filepath.Join(t.TempDir(), "missing.json")
+	_, err := a.RunArgs([]string{"join", "--config=" + missing})
+	if _, ok := err.(*ConfigError); !ok {
+		t.Errorf("missing explicit config resulted in %v", err)
+	}
+
+	ioutil.WriteFile(other, []byte(`{"join": `), 0644)
+	_, err = a.RunArgs([]string{"join", "--config=" + other})
+	if _, ok := err.(*ConfigError); !ok {
+		t.Errorf("malformed config resulted in %v", err)
+	}
+
+	a.Config.Path = missing
+	if _, err := a.RunArgs([]string{"join"}); err != nil {
+		t.Errorf("missing default config resulted in %v", err)
+	}
+}
+
+func TestConfig_Topic(t *testing.T) {
+	var ctx Context
+	a := newConfigApp(t, &ctx)
+	defer output.Reset()
+
+	a.RunArgs([]string{"help"})
+	if !strings.Contains(output.String(), "config      configuration file keys") {
+		t.Errorf("config topic is not listed:\n%s", output.String())
+	}
+
+	if !strings.Contains(output.String(), `--config="file"`) {
+		t.Errorf("--config option is not listed:\n%s", output.String())
+	}
+
+	output.Reset()
+	a.RunArgs([]string{"help", "config"})
+
+	for _, key := range []string{"\tverbose\n", "\tjoin.separator\n", "\tremote.add.name\n\t\tRemote name."} {
+		if !strings.Contains(output.String(), key) {
+			t.Errorf("config topic doesn't document %q:\n%s", key, output.String())
+		}
+	}
+}
+
+func TestConfig_TopicCommand(t *testing.T) {
+	a := newTestApp("app")
+	a.Config = &Config{Path: filepath.Join(t.TempDir(), "config.json")}
+	a.AddCommand(Command{Name: "settings", Brief: "edits the configuration", Aliases: []string{"config"}})
+	defer output.Reset()
+
+	a.RunArgs([]string{"help"})
+	if strings.Contains(output.String(), "configuration file keys") {
+		t.Errorf("config topic is listed along with the config command:\n%s", output.String())
+	}
+
+	output.Reset()
+	a.RunArgs([]string{"help", "config"})
+	if !strings.Contains(output.String(), "Aliases: config") {
+		t.Errorf("help config doesn't show the command:\n%s", output.String())
+	}
+}
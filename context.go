@@ -2,8 +2,13 @@ package climax
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"io"
+	"os"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // Context is a set of arguments and options of command call.
@@ -21,7 +26,45 @@ type Context struct {
 	NonVariable map[string]bool
 	Variable    map[string]string
 
-	app *Application
+	app     *Application
+	ctx     context.Context
+	lists   map[string][]string
+	sources map[string]Source
+}
+
+// Source tells where the value of a flag came from.
+type Source int
+
+const (
+	// SourceNone means the flag is not defined at all.
+	SourceNone Source = iota
+
+	// SourceDefault means the flag holds its default value.
+	SourceDefault
+
+	// SourceConfig means the flag is set by the configuration file.
+	SourceConfig
+
+	// SourceEnvironment means the flag is set by an environment variable.
+	SourceEnvironment
+
+	// SourceCommandLine means the flag is present on the command line.
+	SourceCommandLine
+)
+
+func (s Source) String() string {
+	switch s {
+	case SourceDefault:
+		return "default"
+	case SourceConfig:
+		return "config"
+	case SourceEnvironment:
+		return "environment"
+	case SourceCommandLine:
+		return "command line"
+	}
+
+	return "none"
 }
 
 // Log prints the message to stderrr (each argument takes a distinct line).
@@ -29,6 +72,51 @@ func (c *Context) Log(data ...interface{}) {
 	c.app.Log(data...)
 }
 
+// Context returns the context of the run. It's cancelled once
+// the application is interrupted by SIGINT or SIGTERM, or the
+// time given by the --timeout option of the command runs out.
+func (c *Context) Context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+
+	return c.ctx
+}
+
+// WithContext returns a copy of the context, carrying the
+// context.Context given.
+func (c Context) WithContext(ctx context.Context) Context {
+	c.ctx = ctx
+	return c
+}
+
+// Stdout returns the standard output of the application.
+func (c *Context) Stdout() io.Writer {
+	if c.app == nil {
+		return os.Stdout
+	}
+
+	return c.app.stdout()
+}
+
+// Stderr returns the error output of the application.
+func (c *Context) Stderr() io.Writer {
+	if c.app == nil {
+		return os.Stderr
+	}
+
+	return c.app.stderr()
+}
+
+// Stdin returns the standard input of the application.
+func (c *Context) Stdin() io.Reader {
+	if c.app == nil {
+		return os.Stdin
+	}
+
+	return c.app.stdin()
+}
+
 // Is returns true if a flag with corresponding name is defined.
 func (c *Context) Is(flagName string) bool {
 	if _, ok := c.NonVariable[flagName]; ok {
@@ -42,6 +130,19 @@ func (c *Context) Is(flagName string) bool {
 	return false
 }
 
+// Source tells where the value of corresponding flag came from.
+func (c *Context) Source(flagName string) Source {
+	if source, ok := c.sources[flagName]; ok {
+		return source
+	}
+
+	if c.Is(flagName) {
+		return SourceCommandLine
+	}
+
+	return SourceNone
+}
+
 // Get returns a value of corresponding variable flag.
 // Second (bool) parameter says whether it's really defined or not.
 func (c *Context) Get(variableFlagName string) (string, bool) {
@@ -49,12 +150,151 @@ func (c *Context) Get(variableFlagName string) (string, bool) {
 	return value, ok
 }
 
+// GetAll returns all the values of corresponding variable flag.
+//
+// Repeatable flags collect every value given, others hold at
+// most one value. It returns nil if the flag is not defined.
+func (c *Context) GetAll(variableFlagName string) []string {
+	if values, ok := c.lists[variableFlagName]; ok {
+		return values
+	}
+
+	if value, ok := c.Variable[variableFlagName]; ok {
+		return []string{value}
+	}
+
+	return nil
+}
+
+// GetInt returns a value of corresponding integer flag.
+// Second (bool) parameter says whether it's really defined
+// and holds a valid integer.
+func (c *Context) GetInt(variableFlagName string) (int, bool) {
+	value, ok := c.Variable[variableFlagName]
+	if !ok {
+		return 0, false
+	}
+
+	n, err := parseInt(value)
+	return n, err == nil
+}
+
+// GetFloat returns a value of corresponding float flag.
+func (c *Context) GetFloat(variableFlagName string) (float64, bool) {
+	value, ok := c.Variable[variableFlagName]
+	if !ok {
+		return 0, false
+	}
+
+	f, err := strconv.ParseFloat(value, 64)
+	return f, err == nil
+}
+
+// GetBool returns a value of corresponding bool flag.
+//
+// It also works for non-variable flags, which are true
+// once they are present on the command line.
+func (c *Context) GetBool(flagName string) (bool, bool) {
+	value, ok := c.Variable[flagName]
+	if !ok {
+		return c.NonVariable[flagName], c.NonVariable[flagName]
+	}
+
+	b, err := parseBool(value)
+	return b, err == nil
+}
+
+// GetDuration returns a value of corresponding duration flag.
+func (c *Context) GetDuration(variableFlagName string) (time.Duration, bool) {
+	value, ok := c.Variable[variableFlagName]
+	if !ok {
+		return 0, false
+	}
+
+	d, err := time.ParseDuration(value)
+	return d, err == nil
+}
+
+// GetSize returns a value of corresponding size flag in bytes.
+func (c *Context) GetSize(variableFlagName string) (int64, bool) {
+	value, ok := c.Variable[variableFlagName]
+	if !ok {
+		return 0, false
+	}
+
+	n, err := parseSize(value)
+	return n, err == nil
+}
+
+// looksLikeFlag tells whether the argument is an option. A lone
+// "-" is not: by convention, it's a positional argument standing
+// for the standard input.
 func looksLikeFlag(flag string) bool {
-	if strings.HasPrefix(flag, "-") || strings.HasPrefix(flag, "--") {
-		return true
+	return len(flag) > 1 && strings.HasPrefix(flag, "-")
+}
+
+func isNegativeNumber(argument string) bool {
+	if !strings.HasPrefix(argument, "-") {
+		return false
 	}
 
-	return false
+	_, err := strconv.ParseFloat(argument, 64)
+	return err == nil
+}
+
+// looksLikeValue tells whether the argument can be a value of
+// the variable flag preceding it.
+func looksLikeValue(argument string) bool {
+	return !looksLikeFlag(argument) || isNegativeNumber(argument)
+}
+
+// needsValue tells whether the flag takes its value from the
+// next argument, as in --filter "token".
+func needsValue(flag *Flag, argument string) bool {
+	if !flag.isVariable() || flag.Type == BoolFlag {
+		return false
+	}
+
+	_, value := parseFlagSignature(argument)
+	return value == "" && !strings.HasSuffix(argument, "=")
+}
+
+// splitShortFlags splits a bundle of single-letter options, like
+// -xvf, into distinct ones. A variable option takes the rest of the
+// bundle as its value, so -ofile is the same as -o=file. It returns
+// nil if the argument is not a bundle.
+func splitShortFlags(flags []Flag, argument string) []string {
+	if strings.HasPrefix(argument, "--") || len(argument) < 3 {
+		return nil
+	}
+
+	var split []string
+	bundle := argument[1:]
+
+	for i := 0; i < len(bundle); i++ {
+		short := bundle[i : i+1]
+
+		flag := flagByName(&flags, short)
+		if flag == nil || flag.Short != short {
+			return nil
+		}
+
+		rest := bundle[i+1:]
+		if flag.isVariable() && flag.Type != BoolFlag && rest != "" {
+			return append(split, "-"+short+"="+strings.TrimPrefix(rest, "="))
+		}
+
+		split = append(split, "-"+short)
+	}
+
+	return split
+}
+
+// splice replaces i-th argument with the ones given.
+func splice(argv []string, i int, arguments []string) []string {
+	spliced := append([]string{}, argv[:i]...)
+	spliced = append(spliced, arguments...)
+	return append(spliced, argv[i+1:]...)
 }
 
 func parseFlagSignature(flag string) (string, string) {
@@ -78,6 +318,40 @@ func flagByName(flags *[]Flag, name string) *Flag {
 	return nil
 }
 
+// flagByName looks the flag up like the package function does,
+// but also resolves the prefixes of long options if the prefix
+// matching is on.
+func (a *Application) flagByName(flags []Flag, argument, name string) (*Flag, error) {
+	if flag := flagByName(&flags, name); flag != nil || !a.PrefixMatching {
+		return flag, nil
+	}
+
+	if !strings.HasPrefix(argument, "--") {
+		return nil, nil
+	}
+
+	var matches []*Flag
+	for i, flag := range flags {
+		if strings.HasPrefix(flag.Name, name) {
+			matches = append(matches, &flags[i])
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, nil
+	case 1:
+		return matches[0], nil
+	}
+
+	var candidates []string
+	for _, flag := range matches {
+		candidates = append(candidates, "--"+flag.Name)
+	}
+
+	return nil, &FlagError{Name: name, Reason: "is ambiguous", Suggestions: candidates}
+}
+
 func newContext(app *Application) *Context {
 	ctx := Context{}
 
@@ -90,49 +364,226 @@ func newContext(app *Application) *Context {
 	return &ctx
 }
 
-func (a *Application) parseContext(flags []Flag, argv []string) (*Context, error) {
+// setValue checks and stores a value of the variable flag.
+func (c *Context) setValue(flag *Flag, value string) error {
+	values := []string{value}
+	if flag.Repeatable && flag.SplitCommas {
+		values = strings.Split(value, ",")
+	}
+
+	for _, each := range values {
+		if err := flag.Type.check(each); err != nil {
+			return err
+		}
+	}
+
+	if flag.Repeatable {
+		if c.lists == nil {
+			c.lists = make(map[string][]string)
+		}
+
+		c.lists[flag.Name] = append(c.lists[flag.Name], values...)
+		value = values[len(values)-1]
+	}
+
+	c.Variable[flag.Name] = value
+	return nil
+}
+
+func (c *Context) setSource(flagName string, source Source) {
+	if c.sources == nil {
+		c.sources = make(map[string]Source)
+	}
+
+	c.sources[flagName] = source
+}
+
+// flagEnv returns names of the environment variables bound to the
+// flag: ones it declares and, given the prefix, an automatic one.
+func flagEnv(flag Flag, prefix string) []string {
+	names := flag.Env
+	if prefix != "" {
+		name := strings.ToUpper(strings.Replace(flag.Name, "-", "_", -1))
+		names = append(names[:len(names):len(names)], prefix+"_"+name)
+	}
+
+	return names
+}
+
+// setFromEnv sets the flag from the first of the environment
+// variables given that is defined.
+func (c *Context) setFromEnv(flag *Flag, names []string) error {
+	for _, name := range names {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+
+		if flag.isVariable() {
+			if err := c.setValue(flag, value); err != nil {
+				return &FlagError{Name: flag.Name, Reason: "from $" + name + " " + err.Error()}
+			}
+		} else {
+			set, err := parseBool(value)
+			if err != nil {
+				reason := fmt.Sprintf("from $%s has invalid value %q: expected bool", name, value)
+				return &FlagError{Name: flag.Name, Reason: reason}
+			}
+
+			// The false value is recorded by the source alone,
+			// so the config can't override it.
+			if set {
+				c.NonVariable[flag.Name] = true
+			}
+		}
+
+		c.setSource(flag.Name, SourceEnvironment)
+		return nil
+	}
+
+	return nil
+}
+
+// parseContext parses the command line against the flags given
+// and fills the rest of them from the environment, configuration
+// file section of the command and defaults, in that order.
+func (a *Application) parseContext(flags []Flag, argv []string, section []string) (*Context, error) {
+	ctx, err := a.parseArgs(flags, argv)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, flag := range flags {
+		if ctx.Is(flag.Name) {
+			continue
+		}
+
+		if err := ctx.setFromEnv(&flag, flagEnv(flag, a.EnvPrefix)); err != nil {
+			return nil, err
+		}
+	}
+
+	if a.Config != nil {
+		tree, err := a.loadConfig(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, flag := range flags {
+			if ctx.Source(flag.Name) != SourceNone {
+				continue
+			}
+
+			if err := ctx.setFromConfig(&flag, tree, section); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, flag := range flags {
+		if ctx.Is(flag.Name) || !flag.isVariable() || flag.Default == "" {
+			continue
+		}
+
+		if err := ctx.setValue(&flag, flag.Default); err != nil {
+			return nil, &FlagError{Name: flag.Name, Reason: "default " + err.Error()}
+		}
+
+		ctx.setSource(flag.Name, SourceDefault)
+	}
+
+	for _, flag := range flags {
+		if flag.Required && !ctx.Is(flag.Name) {
+			return nil, &FlagError{Name: flag.Name, Reason: "is required"}
+		}
+	}
+
+	return ctx, nil
+}
+
+// parseBuiltin checks the global options given to the built-in
+// command, which has no use for them, so none of them is required.
+func (a *Application) parseBuiltin(options []string) error {
+	if len(options) == 0 {
+		return nil
+	}
+
+	_, err := a.parseContext(optionalFlags(a.globalFlags(nil)), options, nil)
+	return err
+}
+
+// optionalFlags returns the copies of the flags, none of which
+// is required.
+func optionalFlags(flags []Flag) []Flag {
+	optional := make([]Flag, len(flags))
+	for i, flag := range flags {
+		flag.Required = false
+		optional[i] = flag
+	}
+
+	return optional
+}
+
+// parseArgs parses the command line alone against the flags given.
+func (a *Application) parseArgs(flags []Flag, argv []string) (*Context, error) {
 	ctx := newContext(a)
 
 	for i := 0; i < len(argv); i++ {
 		argument := argv[i]
 
+		// $ app command -v -- -literal
+		//                  ^ no options beyond this point
+		if argument == "--" {
+			ctx.Args = append(ctx.Args, argv[i+1:]...)
+			break
+		}
+
 		if !looksLikeFlag(argument) {
 			ctx.Args = append(ctx.Args, argument)
 			continue
 		}
 
 		name, value := parseFlagSignature(argument)
-		flag := flagByName(&flags, name)
+		flag, err := a.flagByName(flags, argument, name)
+		if err != nil {
+			return nil, err
+		}
 
 		if flag == nil {
-			return nil, fmt.Errorf(`option -%s does not exist`, name)
-		}
+			if split := splitShortFlags(flags, argument); split != nil {
+				argv = splice(argv, i, split)
+				i--
+				continue
+			}
 
-		if flag.Variable {
-			if value == "" {
-				if strings.HasSuffix(argument, "=") {
-					ctx.Variable[flag.Name] = ""
-					continue
-				}
+			if isNegativeNumber(argument) {
+				ctx.Args = append(ctx.Args, argument)
+				continue
+			}
 
-				if i+1 >= len(argv) {
-					return nil, fmt.Errorf(`option -%s is invalid`, name)
-				}
+			return nil, a.unknownFlag(name, flags)
+		}
 
-				if looksLikeFlag(argv[i+1]) {
-					return nil, fmt.Errorf(`option -%s is invalid`, name)
+		if flag.isVariable() {
+			if value == "" && !strings.HasSuffix(argument, "=") {
+				switch {
+				case flag.Type == BoolFlag:
+					value = "true"
+				case i+1 >= len(argv) || !looksLikeValue(argv[i+1]):
+					return nil, &FlagError{Name: name, Reason: "is invalid"}
+				default:
+					i++
+					value = argv[i]
 				}
-
-				ctx.Variable[flag.Name] = argv[i+1]
-				i++
-				continue
 			}
 
-			ctx.Variable[flag.Name] = value
+			if err := ctx.setValue(flag, value); err != nil {
+				return nil, &FlagError{Name: name, Reason: err.Error()}
+			}
 
 		} else {
 			if value != "" {
-				return nil, fmt.Errorf(`-%s is not variable option`, name)
+				return nil, &FlagError{Name: name, Reason: "is not variable"}
 			}
 
 			ctx.NonVariable[flag.Name] = true
@@ -161,3 +612,107 @@ func (c Context) String() string {
 
 	return b.String()
 }
+
+// leadingFlags splits off the options, known to the flags given,
+// that precede the first non-option argument.
+func (a *Application) leadingFlags(flags []Flag, argv []string) ([]string, []string, error) {
+	for i := 0; i < len(argv); i++ {
+		argument := argv[i]
+
+		if !looksLikeFlag(argument) || argument == "--" {
+			return append([]string{}, argv[:i]...), argv[i:], nil
+		}
+
+		name, _ := parseFlagSignature(argument)
+		flag, err := a.flagByName(flags, argument, name)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if flag == nil {
+			if split := splitShortFlags(flags, argument); split != nil {
+				argv = splice(argv, i, split)
+				i--
+				continue
+			}
+
+			if isNegativeNumber(argument) {
+				return append([]string{}, argv[:i]...), argv[i:], nil
+			}
+
+			return nil, nil, a.unknownFlag(name, flags)
+		}
+
+		if needsValue(flag, argument) && i+1 < len(argv) && looksLikeValue(argv[i+1]) {
+			i++
+		}
+	}
+
+	return append([]string{}, argv...), nil, nil
+}
+
+// parseCommand parses the command line of the innermost command
+// of the path and checks it against the arguments it describes.
+func (a *Application) parseCommand(path []*Command, argv []string) (*Context, error) {
+	command := path[len(path)-1]
+
+	section := strings.Fields(commandName(path))
+
+	ctx, err := a.parseContext(a.commandFlags(path), argv, section)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkArguments(command.Arguments, ctx.Args); err != nil {
+		return nil, err
+	}
+
+	return ctx, nil
+}
+
+func checkArguments(arguments []Argument, values []string) error {
+	if len(arguments) == 0 {
+		return nil
+	}
+
+	for i, argument := range arguments {
+		left := len(values) - i
+
+		if argument.Variadic {
+			min := argument.Min
+			if min == 0 {
+				min = 1
+			}
+
+			switch {
+			case left <= 0 && argument.Optional:
+				// Optional arguments may be omitted altogether.
+			case left < min && min == 1:
+				return &ArgumentError{argument.Name, "is missing"}
+			case left < min:
+				reason := fmt.Sprintf("requires at least %d values", min)
+				return &ArgumentError{argument.Name, reason}
+			case argument.Max > 0 && left > argument.Max:
+				reason := fmt.Sprintf("takes at most %d values", argument.Max)
+				return &ArgumentError{argument.Name, reason}
+			}
+
+			return nil
+		}
+
+		if left <= 0 {
+			if argument.Optional {
+				return nil
+			}
+
+			return &ArgumentError{argument.Name, "is missing"}
+		}
+	}
+
+	if len(values) > len(arguments) {
+		reason := fmt.Sprintf("unexpected argument %q", values[len(arguments)])
+		return &ArgumentError{"", reason}
+	}
+
+	return nil
+}
@@ -1,8 +1,10 @@
 package climax
 
 import (
+	"os"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestContext(t *testing.T) {
@@ -10,7 +12,7 @@ func TestContext(t *testing.T) {
 	check := func(c string, f []Flag, a []string, exp Context) {
 		exp.app = app
 
-		ctx, err := app.parseContext(f, a)
+		ctx, err := app.parseContext(f, a, nil)
 		if err != nil {
 			t.Errorf(`case "%s" didn't finish well:`, c)
 			t.Logf(`error: %s`, err)
@@ -25,7 +27,7 @@ func TestContext(t *testing.T) {
 	}
 
 	mustFail := func(c string, f []Flag, a []string) {
-		_, err := app.parseContext(f, a)
+		_, err := app.parseContext(f, a, nil)
 		if err == nil {
 			t.Errorf(`invalid case "%s" resulted in valid context`, c)
 		}
@@ -135,6 +137,97 @@ func TestContext(t *testing.T) {
 		},
 	})
 
+	check("typed flags", []Flag{
+		Flag{Name: "port", Type: IntFlag},
+		Flag{Name: "debug", Type: BoolFlag},
+		Flag{Name: "timeout", Type: DurationFlag, Default: "1m"},
+	}, []string{"--port", "8080", "--debug", "target"}, Context{
+		Args: []string{"target"},
+		Variable: map[string]string{
+			"port":    "8080",
+			"debug":   "true",
+			"timeout": "1m",
+		},
+		NonVariable: map[string]bool{},
+		sources: map[string]Source{
+			"timeout": SourceDefault,
+		},
+	})
+
+	check("bool flag value", []Flag{
+		Flag{Name: "debug", Type: BoolFlag},
+	}, []string{"--debug", "false"}, Context{
+		Args: []string{"false"},
+		Variable: map[string]string{
+			"debug": "true",
+		},
+		NonVariable: map[string]bool{},
+	})
+
+	check("default overridden", []Flag{
+		Flag{Name: "limit", Type: SizeFlag, Default: "1k"},
+	}, []string{"--limit=2MiB"}, Context{
+		Args: []string{},
+		Variable: map[string]string{
+			"limit": "2MiB",
+		},
+		NonVariable: map[string]bool{},
+	})
+
+	check("terminator", []Flag{
+		Flag{Name: "verbose", Short: "v"},
+	}, []string{"-v", "--", "-v", "--verbose", "-"}, Context{
+		Args: []string{"-v", "--verbose", "-"},
+		NonVariable: map[string]bool{
+			"verbose": true,
+		},
+		Variable: map[string]string{},
+	})
+
+	check("stdin argument", []Flag{}, []string{"-"}, Context{
+		Args:        []string{"-"},
+		NonVariable: map[string]bool{},
+		Variable:    map[string]string{},
+	})
+
+	check("bundled short flags", []Flag{
+		Flag{Name: "extract", Short: "x"},
+		Flag{Name: "verbose", Short: "v"},
+		Flag{Name: "file", Short: "f", Variable: true},
+	}, []string{"-xvf", "archive.tar", "member"}, Context{
+		Args: []string{"member"},
+		NonVariable: map[string]bool{
+			"extract": true,
+			"verbose": true,
+		},
+		Variable: map[string]string{
+			"file": "archive.tar",
+		},
+	})
+
+	check("attached short value", []Flag{
+		Flag{Name: "verbose", Short: "v"},
+		Flag{Name: "output", Short: "o", Variable: true},
+	}, []string{"-ofile", "-vo=other", "x"}, Context{
+		Args: []string{"x"},
+		NonVariable: map[string]bool{
+			"verbose": true,
+		},
+		Variable: map[string]string{
+			"output": "other",
+		},
+	})
+
+	check("negative numbers", []Flag{
+		Flag{Name: "offset", Type: IntFlag},
+	}, []string{"-5", "--offset", "-10", "-2.5"}, Context{
+		Args: []string{"-5", "-2.5"},
+		Variable: map[string]string{
+			"offset": "-10",
+		},
+		NonVariable: map[string]bool{},
+	})
+
 	// FAIL TESTS
 	// ==========
 
@@ -142,7 +235,238 @@ func TestContext(t *testing.T) {
 		Flag{Name: "force", Variable: false},
 	}, []string{"--force=value"})
 
+	mustFail("unknown flag in a bundle", []Flag{
+		Flag{Name: "verbose", Short: "v"},
+	}, []string{"-vq"})
+
 	mustFail("missing var flag value", []Flag{
 		Flag{Name: "filter", Variable: true},
 	}, []string{"--filter"})
+
+	mustFail("malformed int flag", []Flag{
+		Flag{Name: "port", Type: IntFlag},
+	}, []string{"--port=http"})
+
+	mustFail("malformed default", []Flag{
+		Flag{Name: "timeout", Type: DurationFlag, Default: "soon"},
+	}, []string{})
+}
+
+func TestContext_Typed(t *testing.T) {
+	app := &Application{}
+	ctx, err := app.parseContext([]Flag{
+		{Name: "port", Type: IntFlag},
+		{Name: "ratio", Type: FloatFlag},
+		{Name: "debug", Type: BoolFlag},
+		{Name: "force"},
+		{Name: "timeout", Type: DurationFlag},
+		{Name: "limit", Type: SizeFlag},
+	}, []string{
+		"--port=0x10", "--ratio=0.5", "--debug=off", "--force",
+		"--timeout=1m30s", "--limit=1.5k",
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n, ok := ctx.GetInt("port"); !ok || n != 16 {
+		t.Errorf("GetInt: got (%d, %v)", n, ok)
+	}
+
+	if f, ok := ctx.GetFloat("ratio"); !ok || f != 0.5 {
+		t.Errorf("GetFloat: got (%f, %v)", f, ok)
+	}
+
+	if b, ok := ctx.GetBool("debug"); !ok || b {
+		t.Errorf("GetBool: got (%v, %v)", b, ok)
+	}
+
+	if b, ok := ctx.GetBool("force"); !ok || !b {
+		t.Errorf("GetBool on non-variable flag: got (%v, %v)", b, ok)
+	}
+
+	if d, ok := ctx.GetDuration("timeout"); !ok || d != 90*time.Second {
+		t.Errorf("GetDuration: got (%s, %v)", d, ok)
+	}
+
+	if n, ok := ctx.GetSize("limit"); !ok || n != 1500 {
+		t.Errorf("GetSize: got (%d, %v)", n, ok)
+	}
+
+	if _, ok := ctx.GetInt("missing"); ok {
+		t.Errorf("GetInt succeeded on a missing flag")
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	cases := map[string]int64{
+		"512":    512,
+		"512B":   512,
+		"10k":    10000,
+		"10KB":   10000,
+		"1KiB":   1024,
+		"1.5GiB": 3 << 29,
+		"2 mi":   2 << 20,
+	}
+
+	for input, expected := range cases {
+		if n, err := parseSize(input); err != nil || n != expected {
+			t.Errorf("parseSize(%q) = (%d, %v), expected %d", input, n, err, expected)
+		}
+	}
+
+	for _, input := range []string{"", "k", "10x", "1..2m", "8192PiB"} {
+		if _, err := parseSize(input); err == nil {
+			t.Errorf("parseSize(%q) didn't fail", input)
+		}
+	}
+}
+
+func TestContext_Repeatable(t *testing.T) {
+	app := &Application{}
+	ctx, err := app.parseContext([]Flag{
+		{Name: "include", Short: "I", Variable: true, Repeatable: true},
+		{Name: "tag", Variable: true, Repeatable: true, SplitCommas: true},
+		{Name: "port", Type: IntFlag, Repeatable: true, Default: "80"},
+		{Name: "output", Variable: true},
+	}, []string{
+		"-I", "dir1", "-I=dir2", "--tag", "a,b", "--tag=c",
+		"--output=first", "--output=second",
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string][]string{
+		"include": {"dir1", "dir2"},
+		"tag":     {"a", "b", "c"},
+		"port":    {"80"},
+		"output":  {"second"},
+		"missing": nil,
+	}
+
+	for name, values := range expected {
+		if actual := ctx.GetAll(name); !reflect.DeepEqual(actual, values) {
+			t.Errorf("GetAll(%q) = %q, expected %q", name, actual, values)
+		}
+	}
+
+	if value, _ := ctx.Get("include"); value != "dir2" {
+		t.Errorf("Get returned %q instead of the last value", value)
+	}
+
+	_, err = app.parseContext([]Flag{
+		{Name: "port", Type: IntFlag, Repeatable: true, SplitCommas: true},
+	}, []string{"--port=80,http"}, nil)
+	if err == nil {
+		t.Errorf("malformed comma-separated value didn't fail")
+	}
+}
+
+func TestCheckArguments(t *testing.T) {
+	copying := []Argument{
+		{Name: "source"},
+		{Name: "destination", Optional: true},
+	}
+	archiving := []Argument{
+		{Name: "archive"},
+		{Name: "files", Variadic: true, Max: 3},
+	}
+	pairs := []Argument{
+		{Name: "pairs", Variadic: true, Optional: true, Min: 2},
+	}
+
+	cases := []struct {
+		arguments []Argument
+		values    []string
+		valid     bool
+	}{
+		{nil, []string{"anything", "goes"}, true},
+		{copying, []string{"a"}, true},
+		{copying, []string{"a", "b"}, true},
+		{copying, []string{}, false},
+		{copying, []string{"a", "b", "c"}, false},
+		{archiving, []string{"a.tar", "x"}, true},
+		{archiving, []string{"a.tar", "x", "y", "z"}, true},
+		{archiving, []string{"a.tar"}, false},
+		{archiving, []string{"a.tar", "x", "y", "z", "w"}, false},
+		{pairs, []string{}, true},
+		{pairs, []string{"a"}, false},
+		{pairs, []string{"a", "b"}, true},
+	}
+
+	for _, c := range cases {
+		err := checkArguments(c.arguments, c.values)
+		if (err == nil) != c.valid {
+			t.Errorf("checkArguments(%q) = %v, expected valid=%v", c.values, err, c.valid)
+		}
+	}
+}
+
+func TestContext_Required(t *testing.T) {
+	app := &Application{}
+	flags := []Flag{
+		{Name: "name", Variable: true, Required: true},
+		{Name: "region", Variable: true, Required: true, Default: "eu"},
+	}
+
+	if _, err := app.parseContext(flags, []string{"--name=x"}, nil); err != nil {
+		t.Errorf("valid command line failed: %v", err)
+	}
+
+	_, err := app.parseContext(flags, []string{"--region=us"}, nil)
+	if e, ok := err.(*FlagError); !ok || e.Name != "name" {
+		t.Errorf("missing required flag resulted in %v", err)
+	}
+}
+
+func TestContext_Env(t *testing.T) {
+	os.Setenv("TEST_SEPARATOR", ".")
+	os.Setenv("CLIMAX_TEST_FORCE", "yes")
+	os.Setenv("CLIMAX_TEST_PORT", "http")
+	defer os.Unsetenv("TEST_SEPARATOR")
+	defer os.Unsetenv("CLIMAX_TEST_FORCE")
+	defer os.Unsetenv("CLIMAX_TEST_PORT")
+
+	app := &Application{EnvPrefix: "CLIMAX_TEST"}
+	flags := []Flag{
+		{Name: "separator", Variable: true, Env: []string{"TEST_MISSING", "TEST_SEPARATOR"}},
+		{Name: "force"},
+		{Name: "output", Variable: true, Default: "-"},
+		{Name: "name", Variable: true},
+		{Name: "verbose"},
+	}
+
+	ctx, err := app.parseContext(flags, []string{"--name=x"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if value, _ := ctx.Get("separator"); value != "." || !ctx.Is("force") {
+		t.Errorf("environment didn't apply:\n%s", ctx)
+	}
+
+	sources := map[string]Source{
+		"separator": SourceEnvironment,
+		"force":     SourceEnvironment,
+		"output":    SourceDefault,
+		"name":      SourceCommandLine,
+		"verbose":   SourceNone,
+	}
+
+	for name, source := range sources {
+		if actual := ctx.Source(name); actual != source {
+			t.Errorf("flag %s came from %s, expected %s", name, actual, source)
+		}
+	}
+
+	ctx, err = app.parseContext(flags, []string{"--separator=,"}, nil)
+	if value, _ := ctx.Get("separator"); err != nil || value != "," {
+		t.Errorf("command line didn't take precedence over environment")
+	}
+
+	_, err = app.parseContext([]Flag{{Name: "port", Type: IntFlag}}, []string{}, nil)
+	if err == nil {
+		t.Errorf("malformed environment value didn't fail")
+	}
 }
@@ -0,0 +1,77 @@
+package climax
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// ExitPanic is the exit code of the runs recovered from a panic,
+// EX_SOFTWARE of sysexits.h.
+const ExitPanic = 70
+
+// DebugEnv is the environment variable that turns the panic
+// recovery off, so the panics crash the process as usual.
+//
+// Example: CLIMAX_DEBUG=1 app command
+const DebugEnv = "CLIMAX_DEBUG"
+
+// PanicError is returned when the application recovers from
+// a panic, see Application.RecoverPanics.
+type PanicError struct {
+	// Value is the value the code panicked with.
+	Value interface{}
+
+	// Stack is the stack trace of the panicking goroutine.
+	Stack []byte
+
+	// Report is the path of the crash report, empty if it
+	// couldn't be written.
+	Report string
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("internal error: %v", e.Value)
+}
+
+// debugging tells whether the panic recovery is turned off
+// by the environment.
+func debugging() bool {
+	debug, err := parseBool(os.Getenv(DebugEnv))
+	return err == nil && debug
+}
+
+// recovering tells whether the panics are to be recovered.
+func (a *Application) recovering() bool {
+	return a.RecoverPanics && !debugging()
+}
+
+// crash writes the crash report of the panic.
+func (a *Application) crash(e *PanicError, arguments []string) *PanicError {
+	dir := a.CrashDir
+	if dir == "" {
+		dir = os.TempDir()
+	}
+
+	file, err := ioutil.TempFile(expandHome(dir), a.Name+"-crash-*.txt")
+	if err != nil {
+		return e
+	}
+	defer file.Close()
+
+	quoted := []string{a.Name}
+	for _, argument := range arguments {
+		quoted = append(quoted, strconv.Quote(argument))
+	}
+
+	fmt.Fprintf(file, "%s %s panicked: %v\n\n", a.Name, a.Version, e.Value)
+	fmt.Fprintf(file, "Command line: %s\n", strings.Join(quoted, " "))
+	fmt.Fprintf(file, "Go: %s %s/%s\n\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	file.Write(e.Stack)
+
+	e.Report = file.Name()
+	return e
+}
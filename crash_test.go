@@ -0,0 +1,117 @@
+package climax
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRun_Panic(t *testing.T) {
+	a := newTestApp("git")
+	a.Version = "2.0"
+	a.RecoverPanics = true
+	a.CrashDir = t.TempDir()
+	a.AddCommand(Command{
+		Name:   "push",
+		Handle: func(ctx Context) int { panic("boom") },
+	})
+	a.AddCommand(Command{
+		Name:   "pull",
+		Handle: func(ctx Context) int { return 0 },
+		Before: func(ctx *Context) error { panic(errors.New("hook failed")) },
+	})
+	defer output.Reset()
+
+	setArguments("push", "--", "origin master")
+	if exitcode := a.Run(); exitcode != ExitPanic {
+		t.Errorf("panic resulted in exit code %d", exitcode)
+	}
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(lines) != 2 || lines[0] != "git: internal error: boom" ||
+		!strings.HasPrefix(lines[1], "git: crash report is written to "+a.CrashDir) {
+		t.Fatalf("panic was reported as %q", output.String())
+	}
+
+	report, err := ioutil.ReadFile(strings.TrimPrefix(lines[1], "git: crash report is written to "))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, fragment := range []string{
+		"git 2.0 panicked: boom\n",
+		`Command line: git "push" "--" "origin master"`,
+		"goroutine ",
+		"crash_test.go",
+	} {
+		if !strings.Contains(string(report), fragment) {
+			t.Errorf("crash report lacks %q:\n%s", fragment, report)
+		}
+	}
+
+	_, err = a.RunArgs([]string{"pull"})
+	if e, ok := err.(*PanicError); !ok || e.Error() != "internal error: hook failed" {
+		t.Errorf("hook panic resulted in %v", err)
+	}
+}
+
+func TestRun_PanicFinally(t *testing.T) {
+	var observed []int
+	finally := func(ctx Context, exitcode int) {
+		observed = append(observed, exitcode)
+	}
+
+	a := newTestApp("git")
+	a.RecoverPanics = true
+	a.CrashDir = t.TempDir()
+	a.Finally = finally
+	a.AddCommand(Command{
+		Name:    "push",
+		Handle:  func(ctx Context) int { panic("boom") },
+		Finally: finally,
+	})
+	a.AddCommand(Command{
+		Name:   "pull",
+		Handle: func(ctx Context) int { return 0 },
+		Before: func(ctx *Context) error { panic(errors.New("hook failed")) },
+	})
+	defer output.Reset()
+
+	for _, name := range []string{"push", "pull"} {
+		exitcode, err := a.RunArgs([]string{name})
+		if e, ok := err.(*PanicError); !ok || e.Report == "" || exitcode != ExitPanic {
+			t.Errorf("%s resulted in %d, %v", name, exitcode, err)
+		}
+	}
+
+	if !reflect.DeepEqual(observed, []int{ExitPanic, ExitPanic, ExitPanic}) {
+		t.Errorf("finally hooks observed exit codes %v", observed)
+	}
+}
+
+func TestRun_PanicDebug(t *testing.T) {
+	a := newTestApp("git")
+	a.RecoverPanics = true
+	a.AddCommand(Command{
+		Name:   "push",
+		Handle: func(ctx Context) int { panic("boom") },
+	})
+	defer output.Reset()
+
+	os.Setenv(DebugEnv, "1")
+	defer os.Unsetenv(DebugEnv)
+
+	mustPanic(t, "debug", func() {
+		a.RunArgs([]string{"push"})
+	})
+
+	a.RecoverPanics = false
+	os.Unsetenv(DebugEnv)
+
+	mustPanic(t, "no recovery", func() {
+		a.RunArgs([]string{"push"})
+	})
+}
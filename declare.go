@@ -0,0 +1,212 @@
+package climax
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var (
+	contextType  = reflect.TypeOf(Context{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// StructCommand declares the options of the command with a struct
+// and returns the command with the flags and the handler set.
+//
+// The handler must be a function taking a pointer to the options
+// struct and the context, and returning the exit code:
+//
+//	type cloneOptions struct {
+//	    Depth   int           `short:"d" help:"Limit the history."`
+//	    Branch  string        `flag:"branch" default:"main"`
+//	    Timeout time.Duration `env:"CLONE_TIMEOUT"`
+//	    Quiet   bool          `short:"q"`
+//	    Tags    []string      `help:"Fetch the tags given."`
+//	    Origin  string        `required:"true"`
+//	}
+//
+//	climax.StructCommand(climax.Command{Name: "clone"},
+//	    func(opts *cloneOptions, ctx climax.Context) int { ... })
+//
+// Every exported field becomes a flag, named after the field in
+// kebab case, e.g. "dry-run" for DryRun, unless the flag tag says
+// otherwise; the "-" name leaves the field out. The short, help,
+// default, env (comma-separated) and required tags fill in the
+// corresponding Flag members.
+//
+// Supported field types are string, bool, int, int64, float64,
+// time.Duration, and slices of string and int, the latter being
+// repeatable flags. The handler receives the struct filled in
+// with the values, already checked by the parser. StructCommand
+// panics if the handler or the struct doesn't fit.
+func StructCommand(command Command, handler interface{}) Command {
+	handle := reflect.ValueOf(handler)
+
+	t := handle.Type()
+	if t.Kind() != reflect.Func || t.NumIn() != 2 || t.NumOut() != 1 ||
+		t.In(0).Kind() != reflect.Ptr || t.In(0).Elem().Kind() != reflect.Struct ||
+		t.In(1) != contextType || t.Out(0).Kind() != reflect.Int {
+		panic("struct command handler must be func(*struct, climax.Context) int")
+	}
+
+	options := t.In(0).Elem()
+	fields := structFlags(options)
+
+	for _, field := range fields {
+		command.Flags = append(command.Flags, field.flag)
+	}
+
+	command.Handle = func(ctx Context) int {
+		value := reflect.New(options)
+		for _, field := range fields {
+			field.fill(value.Elem().Field(field.index), &ctx)
+		}
+
+		out := handle.Call([]reflect.Value{value, reflect.ValueOf(ctx)})
+		return int(out[0].Int())
+	}
+
+	return command
+}
+
+// structField binds a field of the options struct to its flag.
+type structField struct {
+	index int
+	flag  Flag
+}
+
+func structFlags(options reflect.Type) []structField {
+	var fields []structField
+	for i := 0; i < options.NumField(); i++ {
+		field := options.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name := field.Tag.Get("flag")
+		if name == "-" {
+			continue
+		}
+
+		if name == "" {
+			name = kebabCase(field.Name)
+		}
+
+		flag := Flag{
+			Name:     name,
+			Short:    field.Tag.Get("short"),
+			Help:     field.Tag.Get("help"),
+			Default:  field.Tag.Get("default"),
+			Variable: true,
+		}
+
+		if env := field.Tag.Get("env"); env != "" {
+			flag.Env = strings.Split(env, ",")
+		}
+
+		if required, err := parseBool(field.Tag.Get("required")); err == nil {
+			flag.Required = required
+		}
+
+		typ := field.Type
+		if typ.Kind() == reflect.Slice {
+			flag.Repeatable = true
+			typ = typ.Elem()
+		}
+
+		kind := typ.Kind()
+		switch {
+		case typ == durationType && !flag.Repeatable:
+			flag.Type = DurationFlag
+		case kind == reflect.String:
+			flag.Type = StringFlag
+		case kind == reflect.Bool && !flag.Repeatable:
+			flag.Type = BoolFlag
+		case kind == reflect.Int, kind == reflect.Int64 && typ != durationType:
+			flag.Type = IntFlag
+		case kind == reflect.Float64 && !flag.Repeatable:
+			flag.Type = FloatFlag
+		default:
+			panic(fmt.Sprintf("field %s has unsupported type %s", field.Name, field.Type))
+		}
+
+		if flag.Default != "" {
+			if err := flag.Type.check(flag.Default); err != nil {
+				panic(fmt.Sprintf("field %s default %s", field.Name, err))
+			}
+		}
+
+		fields = append(fields, structField{i, flag})
+	}
+
+	return fields
+}
+
+// fill sets the field to the value of the flag.
+func (f structField) fill(field reflect.Value, ctx *Context) {
+	name := f.flag.Name
+
+	if f.flag.Repeatable {
+		values := ctx.GetAll(name)
+		if len(values) == 0 {
+			return
+		}
+
+		slice := reflect.MakeSlice(field.Type(), len(values), len(values))
+		for i, value := range values {
+			if f.flag.Type == IntFlag {
+				n, _ := parseInt(value)
+				slice.Index(i).SetInt(int64(n))
+			} else {
+				slice.Index(i).SetString(value)
+			}
+		}
+
+		field.Set(slice)
+		return
+	}
+
+	switch f.flag.Type {
+	case StringFlag:
+		value, _ := ctx.Get(name)
+		field.SetString(value)
+	case BoolFlag:
+		value, _ := ctx.GetBool(name)
+		field.SetBool(value)
+	case IntFlag:
+		value, _ := ctx.GetInt(name)
+		field.SetInt(int64(value))
+	case FloatFlag:
+		value, _ := ctx.GetFloat(name)
+		field.SetFloat(value)
+	case DurationFlag:
+		value, _ := ctx.GetDuration(name)
+		field.SetInt(int64(value))
+	}
+}
+
+// kebabCase turns a Go identifier into a flag name, e.g.
+// DryRun into "dry-run" and HTTPProxy into "http-proxy".
+func kebabCase(name string) string {
+	runes := []rune(name)
+
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			lowerBefore := i > 0 && !unicode.IsUpper(runes[i-1])
+			lowerAfter := i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if lowerBefore || lowerAfter {
+				b.WriteByte('-')
+			}
+
+			r = unicode.ToLower(r)
+		}
+
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
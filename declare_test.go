@@ -0,0 +1,121 @@
+package climax
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testOptions struct {
+	Depth   int           `short:"d" help:"Limit the history."`
+	Branch  string        `default:"main"`
+	Timeout time.Duration `env:"CLIMAX_TEST_TIMEOUT"`
+	Quiet   bool          `short:"q"`
+	Ratio   float64
+	Tags    []string
+	Ports   []int `flag:"port"`
+	Users   []int64
+	DryRun  bool
+	Origin  string `required:"true"`
+	Skipped string `flag:"-"`
+	hidden  string
+}
+
+func TestStructCommand(t *testing.T) {
+	var received *testOptions
+	command := StructCommand(Command{Name: "clone"}, func(opts *testOptions, ctx Context) int {
+		received = opts
+		return 7
+	})
+
+	var names []string
+	for _, flag := range command.Flags {
+		names = append(names, flag.Name)
+	}
+
+	expected := []string{"depth", "branch", "timeout", "quiet", "ratio", "tags", "port", "users", "dry-run", "origin"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("struct resulted in flags %q, expected %q", names, expected)
+	}
+
+	a := newTestApp("git")
+	a.AddCommand(command)
+	defer output.Reset()
+
+	os.Setenv("CLIMAX_TEST_TIMEOUT", "1m")
+	defer os.Unsetenv("CLIMAX_TEST_TIMEOUT")
+
+	exitcode, err := a.RunArgs([]string{"clone", "-qd", "3", "--ratio=.5", "--tags=a", "--tags", "b",
+		"--port=80", "--port=0x1bb", "--users=1", "--users=2", "--dry-run", "--origin=upstream"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if exitcode != 7 {
+		t.Errorf("handler exit code was lost: %d", exitcode)
+	}
+
+	filled := &testOptions{
+		Depth:   3,
+		Branch:  "main",
+		Timeout: time.Minute,
+		Quiet:   true,
+		Ratio:   .5,
+		Tags:    []string{"a", "b"},
+		Ports:   []int{80, 443},
+		Users:   []int64{1, 2},
+		DryRun:  true,
+		Origin:  "upstream",
+	}
+
+	if !reflect.DeepEqual(received, filled) {
+		t.Errorf("handler received %+v, expected %+v", received, filled)
+	}
+
+	if _, err := a.RunArgs([]string{"clone"}); err == nil {
+		t.Errorf("required struct flag wasn't checked")
+	}
+
+	if _, err := a.RunArgs([]string{"clone", "--origin=x", "--depth=deep"}); err == nil {
+		t.Errorf("typed struct flag wasn't checked")
+	}
+}
+
+func TestStructCommand_Invalid(t *testing.T) {
+	handlers := []interface{}{
+		func(ctx Context) int { return 0 },
+		func(opts testOptions, ctx Context) int { return 0 },
+		func(opts *testOptions, ctx Context) {},
+		func(opts *struct{ Channel chan int }, ctx Context) int { return 0 },
+		func(opts *struct{ Delays []time.Duration }, ctx Context) int { return 0 },
+		func(opts *struct {
+			Depth int `default:"deep"`
+		}, ctx Context) int {
+			return 0
+		},
+	}
+
+	for i, handler := range handlers {
+		mustPanic(t, fmt.Sprintf("handler %d", i), func() {
+			StructCommand(Command{Name: "clone"}, handler)
+		})
+	}
+}
+
+func TestKebabCase(t *testing.T) {
+	cases := map[string]string{
+		"Depth":     "depth",
+		"DryRun":    "dry-run",
+		"HTTPProxy": "http-proxy",
+		"UseHTTP":   "use-http",
+		"ID":        "id",
+	}
+
+	for name, expected := range cases {
+		if actual := kebabCase(name); actual != expected {
+			t.Errorf("kebabCase(%q) = %q, expected %q", name, actual, expected)
+		}
+	}
+}
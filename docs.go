@@ -0,0 +1,441 @@
+package climax
+
+import (
+	"fmt"
+	"html"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// docPage is a page of the documentation site, independent of
+// the format it's rendered to.
+type docPage struct {
+	// Name is a file name without the extension, e.g. "command-remote-add".
+	Name   string
+	Title  string
+	Blocks []docBlock
+}
+
+// docBlock is a heading followed by some of: paragraphs of
+// text, a preformatted snippet, a list of links to other pages
+// and a list of terms with descriptions. Empty parts are omitted.
+type docBlock struct {
+	Heading string
+	Text    string
+	Code    string
+	Links   []docLink
+	Terms   []docTerm
+}
+
+type docLink struct {
+	Page        string
+	Text        string
+	Description string
+}
+
+type docTerm struct {
+	Term        string
+	Description string
+}
+
+// MarkdownDocs writes the documentation of the application as
+// a tree of Markdown pages into the directory given, creating it
+// if necessary.
+//
+// The tree consists of index.md, a page per command, including
+// the nested ones (command-remote-add.md), a page per help topic
+// (topic-workflows.md) and a page per group (group-name.md), all
+// linked together. The output only depends on the application,
+// so it can be checked into a repository.
+func (a *Application) MarkdownDocs(dir string) error {
+	return writeDocs(dir, ".md", a.docPages(), markdownPage)
+}
+
+// HTMLDocs writes the same documentation as MarkdownDocs does,
+// but as static HTML pages with no external resources.
+func (a *Application) HTMLDocs(dir string) error {
+	return writeDocs(dir, ".html", a.docPages(), func(page docPage, ext string) string {
+		return htmlPage(a.Name, page, ext)
+	})
+}
+
+func writeDocs(dir, ext string, pages []docPage, render func(docPage, string) string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	for _, page := range pages {
+		path := filepath.Join(dir, page.Name+ext)
+		if err := ioutil.WriteFile(path, []byte(render(page, ext)), 0644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// docSlug turns a name into a file-friendly one, e.g.
+// "Remote Operations" into "remote-operations".
+func docSlug(name string) string {
+	slug := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		}
+
+		return '-'
+	}, name)
+
+	return strings.Trim(slug, "-")
+}
+
+func commandPageName(path []*Command) string {
+	return "command-" + docSlug(strings.Replace(commandName(path), " ", "-", -1))
+}
+
+func (a *Application) docPages() []docPage {
+	pages := []docPage{a.indexDocPage()}
+
+	var walk func(path []*Command)
+	walk = func(path []*Command) {
+		pages = append(pages, a.commandDocPage(path))
+
+		command := path[len(path)-1]
+		for i := range command.Commands {
+			walk(append(path[:len(path):len(path)], &command.Commands[i]))
+		}
+	}
+
+	for i := range a.Commands {
+		walk([]*Command{&a.Commands[i]})
+	}
+
+	for _, topic := range a.topics() {
+		pages = append(pages, a.topicDocPage(topic))
+	}
+
+	for _, group := range a.Groups {
+		pages = append(pages, a.groupDocPage(group))
+	}
+
+	return pages
+}
+
+// commandLinks links the commands, which are nested in the path.
+func commandLinks(path []*Command, commands []Command) []docLink {
+	var links []docLink
+	for i := range commands {
+		subpath := append(path[:len(path):len(path)], &commands[i])
+		links = append(links, docLink{
+			Page:        commandPageName(subpath),
+			Text:        commandName(subpath),
+			Description: aliased(commands[i].Brief, commands[i].Aliases),
+		})
+	}
+
+	return links
+}
+
+func (a *Application) flagTerms(flags []Flag) []docTerm {
+	var terms []docTerm
+	for _, flag := range flags {
+		terms = append(terms, docTerm{flagUsage(flag, false), flagHelp(flag, a.EnvPrefix)})
+	}
+
+	return terms
+}
+
+func (a *Application) indexDocPage() docPage {
+	page := docPage{Name: "index", Title: a.Name}
+	page.Blocks = append(page.Blocks, docBlock{Text: a.Brief})
+
+	usage := a.Name
+	if len(a.Commands) > 0 {
+		usage += " command [arguments]"
+	}
+
+	page.Blocks = append(page.Blocks, docBlock{Heading: "Usage", Code: usage})
+
+	var ungrouped []Command
+	for _, command := range a.Commands {
+		if command.Group == "" {
+			ungrouped = append(ungrouped, command)
+		}
+	}
+
+	if len(ungrouped) > 0 {
+		page.Blocks = append(page.Blocks, docBlock{
+			Heading: "Commands",
+			Links:   commandLinks(nil, ungrouped),
+		})
+	}
+
+	if len(a.Groups) > 0 {
+		block := docBlock{Heading: "Groups"}
+		for _, group := range a.Groups {
+			block.Links = append(block.Links, docLink{Page: "group-" + docSlug(group.Name), Text: group.Name})
+		}
+
+		page.Blocks = append(page.Blocks, block)
+	}
+
+	if flags := a.globalFlags(nil); len(flags) > 0 {
+		page.Blocks = append(page.Blocks, docBlock{Heading: "Global options", Terms: a.flagTerms(flags)})
+	}
+
+	if topics := a.topics(); len(topics) > 0 {
+		block := docBlock{Heading: "Help topics"}
+		for _, topic := range topics {
+			block.Links = append(block.Links, docLink{
+				Page:        "topic-" + docSlug(topic.Name),
+				Text:        topic.Name,
+				Description: topic.Brief,
+			})
+		}
+
+		page.Blocks = append(page.Blocks, block)
+	}
+
+	return page
+}
+
+func (a *Application) commandDocPage(path []*Command) docPage {
+	command := path[len(path)-1]
+	name := commandName(path)
+
+	page := docPage{Name: commandPageName(path), Title: a.Name + " " + name}
+	page.Blocks = append(page.Blocks,
+		docBlock{Text: command.Brief},
+		docBlock{Heading: "Usage", Code: a.Name + " " + commandUsage(name, *command)},
+		docBlock{Text: command.Help})
+
+	if len(command.Aliases) > 0 {
+		page.Blocks = append(page.Blocks, docBlock{Text: "Aliases: " + strings.Join(command.Aliases, ", ")})
+	}
+
+	if len(command.Commands) > 0 {
+		page.Blocks = append(page.Blocks, docBlock{
+			Heading: "Subcommands",
+			Links:   commandLinks(path, command.Commands),
+		})
+	}
+
+	if described(command.Arguments) {
+		block := docBlock{Heading: "Arguments"}
+		for _, argument := range command.Arguments {
+			block.Terms = append(block.Terms, docTerm{argumentUsage(argument), argument.Help})
+		}
+
+		page.Blocks = append(page.Blocks, block)
+	}
+
+	if flags := command.flags(); len(flags) > 0 {
+		page.Blocks = append(page.Blocks, docBlock{Heading: "Options", Terms: a.flagTerms(flags)})
+	}
+
+	if globals := a.globalFlags(path[:len(path)-1]); len(globals) > 0 {
+		page.Blocks = append(page.Blocks, docBlock{Heading: "Global options", Terms: a.flagTerms(globals)})
+	}
+
+	if len(command.Examples) > 0 {
+		block := docBlock{Heading: "Examples"}
+		for _, example := range command.Examples {
+			usecase := "$ " + a.Name + " " + name + " " + example.Usecase
+			block.Terms = append(block.Terms, docTerm{usecase, example.Description})
+		}
+
+		page.Blocks = append(page.Blocks, block)
+	}
+
+	see := docBlock{Heading: "See also"}
+	if len(path) > 1 {
+		parent := path[:len(path)-1]
+		see.Links = append(see.Links, docLink{Page: commandPageName(parent), Text: commandName(parent)})
+	} else {
+		see.Links = append(see.Links, docLink{Page: "index", Text: a.Name})
+	}
+
+	if len(path) == 1 && command.Group != "" {
+		see.Links = append(see.Links, docLink{Page: "group-" + docSlug(command.Group), Text: command.Group})
+	}
+
+	page.Blocks = append(page.Blocks, see)
+	return page
+}
+
+func (a *Application) topicDocPage(topic Topic) docPage {
+	return docPage{
+		Name:  "topic-" + docSlug(topic.Name),
+		Title: topic.Name,
+		Blocks: []docBlock{
+			{Text: topic.Brief},
+			{Text: topic.Text},
+			{Heading: "See also", Links: []docLink{{Page: "index", Text: a.Name}}},
+		},
+	}
+}
+
+func (a *Application) groupDocPage(group Group) docPage {
+	var commands []Command
+	for _, command := range a.Commands {
+		if command.Group == group.Name {
+			commands = append(commands, command)
+		}
+	}
+
+	return docPage{
+		Name:  "group-" + docSlug(group.Name),
+		Title: group.Name,
+		Blocks: []docBlock{
+			{Heading: "Commands", Links: commandLinks(nil, commands)},
+			{Heading: "See also", Links: []docLink{{Page: "index", Text: a.Name}}},
+		},
+	}
+}
+
+// docParagraphs splits the text into blank-line separated
+// paragraphs, telling the indented (preformatted) ones apart.
+func docParagraphs(text string, each func(paragraph string, preformatted bool)) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return
+	}
+
+	for _, paragraph := range strings.Split(text, "\n\n") {
+		preformatted := strings.HasPrefix(paragraph, "\t") || strings.Contains(paragraph, "\n\t")
+		each(paragraph, preformatted)
+	}
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`,
+	"[", `\[`, "]", `\]`, "<", `\<`, ">", `\>`, "#", `\#`)
+
+func markdownCode(text string) string {
+	fence := "```"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+
+	return fence + "\n" + text + "\n" + fence + "\n\n"
+}
+
+func markdownInlineCode(text string) string {
+	if strings.Contains(text, "`") {
+		return "`` " + text + " ``"
+	}
+
+	return "`" + text + "`"
+}
+
+func markdownPage(page docPage, ext string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", markdownEscaper.Replace(page.Title))
+
+	for _, block := range page.Blocks {
+		if block.Heading != "" {
+			fmt.Fprintf(&b, "## %s\n\n", markdownEscaper.Replace(block.Heading))
+		}
+
+		docParagraphs(block.Text, func(paragraph string, preformatted bool) {
+			if preformatted {
+				b.WriteString(markdownCode(paragraph))
+			} else {
+				b.WriteString(markdownEscaper.Replace(paragraph) + "\n\n")
+			}
+		})
+
+		if block.Code != "" {
+			b.WriteString(markdownCode(block.Code))
+		}
+
+		for _, link := range block.Links {
+			fmt.Fprintf(&b, "- [%s](%s%s)", markdownEscaper.Replace(link.Text), link.Page, ext)
+			if link.Description != "" {
+				b.WriteString(" — " + markdownEscaper.Replace(link.Description))
+			}
+
+			b.WriteString("\n")
+		}
+
+		for _, term := range block.Terms {
+			b.WriteString("- " + markdownInlineCode(term.Term))
+			if term.Description != "" {
+				description := strings.Replace(term.Description, "\n", " ", -1)
+				b.WriteString(" — " + markdownEscaper.Replace(description))
+			}
+
+			b.WriteString("\n")
+		}
+
+		if len(block.Links) > 0 || len(block.Terms) > 0 {
+			b.WriteString("\n")
+		}
+	}
+
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
+
+const htmlStyle string = `body{font-family:sans-serif;max-width:48em;margin:2em auto;padding:0 1em;line-height:1.5}
+pre{background:#f4f4f4;padding:.5em 1em;overflow:auto}
+dt{margin-top:.5em}dd{margin-left:2em}`
+
+func htmlPage(app string, page docPage, ext string) string {
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	fmt.Fprintf(&b, "<title>%s</title>\n", html.EscapeString(page.Title))
+	fmt.Fprintf(&b, "<style>\n%s\n</style>\n</head>\n<body>\n", htmlStyle)
+	fmt.Fprintf(&b, "<nav><a href=\"index%s\">%s</a></nav>\n", ext, html.EscapeString(app))
+	fmt.Fprintf(&b, "<h1>%s</h1>\n", html.EscapeString(page.Title))
+
+	for _, block := range page.Blocks {
+		if block.Heading != "" {
+			fmt.Fprintf(&b, "<h2>%s</h2>\n", html.EscapeString(block.Heading))
+		}
+
+		docParagraphs(block.Text, func(paragraph string, preformatted bool) {
+			if preformatted {
+				fmt.Fprintf(&b, "<pre>%s</pre>\n", html.EscapeString(paragraph))
+			} else {
+				fmt.Fprintf(&b, "<p>%s</p>\n", html.EscapeString(paragraph))
+			}
+		})
+
+		if block.Code != "" {
+			fmt.Fprintf(&b, "<pre>%s</pre>\n", html.EscapeString(block.Code))
+		}
+
+		if len(block.Links) > 0 {
+			b.WriteString("<ul>\n")
+			for _, link := range block.Links {
+				fmt.Fprintf(&b, "<li><a href=\"%s%s\">%s</a>", link.Page, ext, html.EscapeString(link.Text))
+				if link.Description != "" {
+					b.WriteString(" — " + html.EscapeString(link.Description))
+				}
+
+				b.WriteString("</li>\n")
+			}
+
+			b.WriteString("</ul>\n")
+		}
+
+		if len(block.Terms) > 0 {
+			b.WriteString("<dl>\n")
+			for _, term := range block.Terms {
+				fmt.Fprintf(&b, "<dt><code>%s</code></dt>\n", html.EscapeString(term.Term))
+				if term.Description != "" {
+					fmt.Fprintf(&b, "<dd>%s</dd>\n", html.EscapeString(term.Description))
+				}
+			}
+
+			b.WriteString("</dl>\n")
+		}
+	}
+
+	b.WriteString("</body>\n</html>\n")
+	return b.String()
+}
@@ -0,0 +1,123 @@
+package climax
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const expectedRemoteAddDoc string = "# git remote add\n" +
+	"\n" +
+	"adds a remote\n" +
+	"\n" +
+	"## Usage\n" +
+	"\n" +
+	"```\n" +
+	"git remote add name url\n" +
+	"```\n" +
+	"\n" +
+	"## Global options\n" +
+	"\n" +
+	"- `-v, --verbose` — Log verbosely.\n" +
+	"\n" +
+	"## Examples\n" +
+	"\n" +
+	"- `$ git remote add origin git://host/repo` — Tracks origin.\n" +
+	"\n" +
+	"## See also\n" +
+	"\n" +
+	"- [remote](command-remote.md)\n"
+
+func TestMarkdownDocs(t *testing.T) {
+	a := newGitApp()
+
+	dir := t.TempDir()
+	if err := a.MarkdownDocs(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	page, err := ioutil.ReadFile(filepath.Join(dir, "command-remote-add.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(page) != expectedRemoteAddDoc {
+		t.Errorf("command page is different to expected:\n%s", page)
+	}
+
+	expected := map[string][]string{
+		"index.md": {
+			"- [init](command-init.md) — creates a repository\n",
+			"- [Plumbing](group-plumbing.md)\n",
+			"- [workflows](topic-workflows.md) — how to collaborate\n",
+		},
+		"command-remote.md": {
+			"- [remote add](command-remote-add.md) — adds a remote\n",
+			"- [git](index.md)\n",
+		},
+		"group-plumbing.md": {
+			"- [cat-file](command-cat-file.md) — shows objects\n",
+		},
+		"command-cat-file.md": {
+			"- [Plumbing](group-plumbing.md)\n",
+		},
+		"topic-workflows.md": {
+			"# workflows\n\nhow to collaborate\n",
+		},
+	}
+
+	for file, fragments := range expected {
+		page, err := ioutil.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+
+		for _, fragment := range fragments {
+			if !strings.Contains(string(page), fragment) {
+				t.Errorf("%s lacks %q:\n%s", file, fragment, page)
+			}
+		}
+	}
+
+	again := t.TempDir()
+	a.MarkdownDocs(again)
+	for _, file := range []string{"index.md", "command-remote.md", "topic-workflows.md"} {
+		first, _ := ioutil.ReadFile(filepath.Join(dir, file))
+		second, _ := ioutil.ReadFile(filepath.Join(again, file))
+		if string(first) != string(second) {
+			t.Errorf("%s is not deterministic", file)
+		}
+	}
+}
+
+func TestHTMLDocs(t *testing.T) {
+	a := newGitApp()
+	a.Commands[0].Help = "Creates <an empty> repository."
+
+	dir := t.TempDir()
+	if err := a.HTMLDocs(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	page, err := ioutil.ReadFile(filepath.Join(dir, "command-init.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, fragment := range []string{
+		"<title>git init</title>",
+		"<p>Creates &lt;an empty&gt; repository.</p>",
+		"<dt><code>--bare</code></dt>\n<dd>Create a bare repository.</dd>",
+		`<li><a href="index.html">git</a></li>`,
+	} {
+		if !strings.Contains(string(page), fragment) {
+			t.Errorf("page lacks %q:\n%s", fragment, page)
+		}
+	}
+
+	if strings.Contains(string(page), "http") {
+		t.Errorf("page refers to external resources:\n%s", page)
+	}
+}
@@ -0,0 +1,174 @@
+package climax
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// UnknownCommandError is returned when there is no command
+// with the name given on the command line.
+type UnknownCommandError struct {
+	Name string
+
+	// Suggestions are the similarly named commands.
+	Suggestions []string
+}
+
+func (e *UnknownCommandError) Error() string {
+	return fmt.Sprintf("unknown subcommand %q", e.Name) + didYouMean(e.Suggestions)
+}
+
+// AmbiguousCommandError is returned when the prefix given on
+// the command line matches several commands.
+type AmbiguousCommandError struct {
+	Name string
+
+	// Candidates are the names of the commands matched.
+	Candidates []string
+}
+
+func (e *AmbiguousCommandError) Error() string {
+	return fmt.Sprintf("ambiguous subcommand %q", e.Name) + didYouMean(e.Candidates)
+}
+
+// UnknownTopicError is returned when help is requested for
+// something that is neither a command nor a topic.
+type UnknownTopicError struct {
+	Name string
+
+	// Suggestions are the similarly named commands and topics.
+	Suggestions []string
+}
+
+func (e *UnknownTopicError) Error() string {
+	return "no such command or help topic" + didYouMean(e.Suggestions)
+}
+
+// FlagError is returned when a command-line option is misused,
+// e.g. it doesn't exist or lacks a value.
+type FlagError struct {
+	// Name is the option as the user typed it, without the prefix.
+	Name string
+
+	// Reason is a short explanation, e.g. "does not exist".
+	Reason string
+
+	// Suggestions are the similarly named options, if the
+	// option doesn't exist.
+	Suggestions []string
+}
+
+func (e *FlagError) Error() string {
+	return fmt.Sprintf("option -%s %s", e.Name, e.Reason) + didYouMean(e.Suggestions)
+}
+
+// ArgumentError is returned when positional arguments don't
+// match the ones described by the command.
+type ArgumentError struct {
+	// Name of the argument, empty if there is no such argument.
+	Name string
+
+	// Reason is a short explanation, e.g. "is missing".
+	Reason string
+}
+
+func (e *ArgumentError) Error() string {
+	if e.Name == "" {
+		return e.Reason
+	}
+
+	return fmt.Sprintf("argument %s %s", e.Name, e.Reason)
+}
+
+// ExitError makes the application exit with the code given.
+//
+// Return it from the handler to pick the exit code. If there
+// is no underlying error, nothing is reported.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("exit status %d", e.Code)
+	}
+
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// UsageError means the command is used the wrong way, so the
+// usage of the command is reported along with the error.
+type UsageError struct {
+	Err error
+
+	// Usage is the usage line of the command, it's filled in
+	// by the application unless the handler provides one.
+	Usage string
+}
+
+func (e *UsageError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *UsageError) Unwrap() error {
+	return e.Err
+}
+
+// exitCode is the default mapping of errors to exit codes.
+func exitCode(err error) int {
+	var exit *ExitError
+	if errors.As(err, &exit) {
+		return exit.Code
+	}
+
+	var crash *PanicError
+	if errors.As(err, &crash) {
+		return ExitPanic
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return ExitInterrupted
+	}
+
+	return 1
+}
+
+func (a *Application) exitCode(err error) int {
+	if a.ExitCodeMapper != nil {
+		return a.ExitCodeMapper(err)
+	}
+
+	return exitCode(err)
+}
+
+// formatError renders the error the way Run reports it.
+func (a *Application) formatError(err error) string {
+	if a.ErrorFormatter != nil {
+		return a.ErrorFormatter(err)
+	}
+
+	var exit *ExitError
+	if errors.As(err, &exit) && exit.Err == nil {
+		return ""
+	}
+
+	message := a.Name + ": " + err.Error()
+
+	var usage *UsageError
+	if errors.As(err, &usage) && usage.Usage != "" {
+		message += "\n\nUsage: " + usage.Usage
+	}
+
+	var crash *PanicError
+	if errors.As(err, &crash) && crash.Report != "" {
+		message += "\n" + a.Name + ": crash report is written to " + crash.Report
+	}
+
+	return message
+}
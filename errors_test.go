@@ -0,0 +1,95 @@
+package climax
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRun_Errors(t *testing.T) {
+	var failure error
+	a := newTestApp("git")
+	a.AddCommand(Command{
+		Name:      "push",
+		Arguments: []Argument{{Name: "remote", Optional: true}},
+		HandleErr: func(ctx Context) error { return failure },
+	})
+	defer output.Reset()
+
+	cases := []struct {
+		failure  error
+		exitcode int
+		message  string
+	}{
+		{nil, 0, ""},
+		{errors.New("rejected"), 1, "git: rejected\n"},
+		{&ExitError{Code: 3, Err: errors.New("conflict")}, 3, "git: conflict\n"},
+		{&ExitError{Code: 4}, 4, ""},
+		{&UsageError{Err: errors.New("remote is unknown")}, 1,
+			"git: remote is unknown\n\nUsage: push [remote]\n"},
+	}
+
+	for _, c := range cases {
+		output.Reset()
+		failure = c.failure
+		setArguments("push")
+
+		if exitcode := a.Run(); exitcode != c.exitcode {
+			t.Errorf("%v resulted in exit code %d, expected %d", c.failure, exitcode, c.exitcode)
+		}
+
+		if output.String() != c.message {
+			t.Errorf("%v was reported as %q, expected %q", c.failure, output.String(), c.message)
+		}
+	}
+}
+
+func TestRun_ErrorFormatter(t *testing.T) {
+	a := newTestApp("git")
+	a.AddCommand(Command{
+		Name: "push",
+		HandleErr: func(ctx Context) error {
+			return &ExitError{Code: 5, Err: errors.New("conflict")}
+		},
+	})
+	defer output.Reset()
+
+	a.ErrorFormatter = func(err error) string {
+		return "error: " + strings.ToUpper(err.Error())
+	}
+
+	a.ExitCodeMapper = func(err error) int {
+		var exit *ExitError
+		if errors.As(err, &exit) {
+			return exit.Code * 10
+		}
+
+		return 2
+	}
+
+	setArguments("push")
+	if exitcode := a.Run(); exitcode != 50 || output.String() != "error: CONFLICT\n" {
+		t.Errorf("custom formatter resulted in %d, %q", exitcode, output.String())
+	}
+
+	output.Reset()
+	setArguments("pull")
+	if exitcode := a.Run(); exitcode != 2 || !strings.HasPrefix(output.String(), "error: UNKNOWN") {
+		t.Errorf("custom mapper resulted in %d, %q", exitcode, output.String())
+	}
+}
+
+func TestCommand_RunErr(t *testing.T) {
+	a := newTestApp("git")
+	command := Command{
+		Name: "push",
+		HandleErr: func(ctx Context) error {
+			return &ExitError{Code: 5, Err: errors.New("conflict")}
+		},
+	}
+	defer output.Reset()
+
+	if exitcode := command.Run(*newContext(a)); exitcode != 5 || output.String() != "git: conflict\n" {
+		t.Errorf("Run resulted in %d, %q", exitcode, output.String())
+	}
+}
@@ -40,7 +40,7 @@ func Example_application() {
 				separator = sep
 			}
 
-			fmt.Println(strings.Join(ctx.Args, separator))
+			fmt.Fprintln(ctx.Stdout(), strings.Join(ctx.Args, separator))
 
 			return 0
 		},
@@ -51,23 +51,62 @@ func Example_application() {
 }
 
 // Handler accepts a climax.Context object and returns an exitcode integer.
-func Example_handler(ctx climax.Context) int {
-	if len(ctx.Args) < 2 {
-		ctx.Log("not enough arguments")
+func Example_handler() {
+	handler := func(ctx climax.Context) int {
+		if len(ctx.Args) < 2 {
+			ctx.Log("not enough arguments")
 
-		// with os.Exit(1)
-		return 1
-	}
+			// with os.Exit(1)
+			return 1
+		}
+
+		if name, ok := ctx.Get("name"); ok {
+			// argument `name` parsed
+			fmt.Fprintln(ctx.Stdout(), name)
 
-	if name, ok := ctx.Get("name"); ok {
-		// argument `name` parsed
-		fmt.Println(name)
+		} else {
+			ctx.Log("name not specified")
 
-	} else {
-		ctx.Log("name not specified")
+			return 1
+		}
 
-		return 1
+		return 0
 	}
 
-	return 0
+	demo := climax.New("demo")
+	demo.AddCommand(climax.Command{
+		Name:   "greet",
+		Flags:  []climax.Flag{{Name: "name", Variable: true}},
+		Handle: handler,
+	})
+	demo.RunArgs([]string{"greet", "--name", "Gopher", "to", "you"})
+	// Output: Gopher
+}
+
+// Arguments and required flags are checked before the handler runs.
+func Example_arguments() {
+	demo := climax.New("demo")
+	demo.AddCommand(climax.Command{
+		Name: "copy",
+		Flags: []climax.Flag{
+			{Name: "mode", Variable: true, Required: true},
+		},
+		Arguments: []climax.Argument{
+			{Name: "target"},
+			{Name: "sources", Variadic: true},
+		},
+		Handle: func(ctx climax.Context) int {
+			mode, _ := ctx.Get("mode")
+			fmt.Fprintln(ctx.Stdout(), mode, ctx.Args)
+			return 0
+		},
+	})
+
+	demo.RunArgs([]string{"copy", "--mode=0644", "dir", "a", "b"})
+
+	_, err := demo.RunArgs([]string{"copy", "--mode=0644", "dir"})
+	fmt.Println(err)
+	// Output:
+	// 0644 [dir a b]
+	// argument sources is missing
 }
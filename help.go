@@ -2,6 +2,7 @@ package climax
 
 import (
 	"bytes"
+	"fmt"
 	"strings"
 	"text/template"
 )
@@ -14,14 +15,17 @@ Usage:
 
 {{if .Commands}}The commands are:
 {{if .UngroupedCount}}{{range .Commands}}
-	{{if not .Group}}{{.Name | printf "%-11s"}} {{.Brief}}{{end}}{{end}}
+	{{if not .Group}}{{.Name | printf "%-11s"}} {{aliased .Brief .Aliases}}{{subcommandList .Name .Commands}}{{end}}{{end}}
 {{end}}{{range .Groups}}{{if .Commands}}
 {{.Name}}
 	{{range .Commands}}
-	{{.Name | printf "%-11s"}} {{.Brief}}{{end}}
+	{{.Name | printf "%-11s"}} {{aliased .Brief .Aliases}}{{subcommandList .Name .Commands}}{{end}}
 	{{end}}{{end}}
 Use "{{.Name}} help [command]" for more information about a command.{{end}}
-{{if .Topics}}
+{{if .Flags}}
+Global options:
+{{flagList .Flags .EnvPrefix}}
+{{end}}{{if .Topics}}
 Additional help topics:
 {{range .Topics}}
 	{{.Name | printf "%-11s"}} {{.Brief}}{{end}}
@@ -29,17 +33,31 @@ Additional help topics:
 Use "{{.Name}} help [topic]" for more information about a topic.
 {{end}}`
 
-const commandHelpTemplate string = `Usage: {{commandUsage .Command}}
-
+const commandHelpTemplate string = `Usage: {{commandUsage .Path .Command}}
+{{if .Aliases}}
+Aliases: {{join .Aliases ", "}}
+{{end}}{{if .Help}}
 {{.Help}}
-{{if .Flags}}
+{{end}}{{if .Commands}}
+The subcommands are:
+{{range .Commands}}
+	{{.Name | printf "%-11s"}} {{aliased .Brief .Aliases}}{{subcommandList .Name .Commands}}{{end}}
+
+Use "{{.App}} help {{.Path}} [command]" for more information about a command.
+{{end}}{{if described .Arguments}}
+Arguments:
+{{range .Arguments}}
+	{{.Name}}
+		{{.Help | tabout}}{{end}}
+{{end}}{{if .Flags}}
 Available options:
-{{range .Flags}}
-	{{flagUsage . false}}
-		{{.Help | tabout}}{{end}}{{end}}
-{{if .Examples}}
+{{flagList .Flags .EnvPrefix}}
+{{end}}{{if .Globals}}
+Global options:
+{{flagList .Globals .EnvPrefix}}
+{{end}}{{if .Examples}}
 Examples:
-{{$app := .App}}{{$cmd := .Name}}{{range .Examples}}
+{{$app := .App}}{{$cmd := .Path}}{{range .Examples}}
 	$ {{$app}} {{$cmd}} {{.Usecase}}
 		{{.Description | tabout}}
 {{end}}{{end}}`
@@ -47,9 +65,13 @@ Examples:
 func templated(canvas string, data interface{}) string {
 	t := template.New("")
 	t.Funcs(template.FuncMap{
-		"tabout":       alignMultilineHelp,
-		"commandUsage": commandUsage,
-		"flagUsage":    flagUsage,
+		"tabout":         alignMultilineHelp,
+		"commandUsage":   commandUsage,
+		"subcommandList": subcommandList,
+		"described":      described,
+		"flagList":       flagList,
+		"aliased":        aliased,
+		"join":           strings.Join,
 	})
 	template.Must(t.Parse(canvas))
 
@@ -60,26 +82,89 @@ func templated(canvas string, data interface{}) string {
 		panic(err)
 	}
 
-	output := b.String()
-
-	// TODO: Fix this nasty workaround for templating ASAP!
-	output = strings.Replace(output, "\n\n\n", "", -1)
-
-	return output
+	return b.String()
 }
 
 func alignMultilineHelp(text string) string {
 	return strings.Replace(text, "\n", "\n\t\t", -1)
 }
 
-func commandUsage(command Command) string {
+// described tells whether any of the arguments has help.
+func described(arguments []Argument) bool {
+	for _, argument := range arguments {
+		if argument.Help != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// subcommandList lists nested commands under their parent,
+// each one on a separate line.
+func subcommandList(parent string, commands []Command) string {
+	var list string
+	for _, command := range commands {
+		name := parent + " " + command.Name
+		list += fmt.Sprintf("\n\t%-11s %s", name, aliased(command.Brief, command.Aliases))
+		list += subcommandList(name, command.Commands)
+	}
+
+	return list
+}
+
+// aliased appends the aliases of the command to its brief.
+func aliased(brief string, aliases []string) string {
+	if len(aliases) == 0 {
+		return brief
+	}
+
+	return brief + " (" + strings.Join(aliases, ", ") + ")"
+}
+
+// commandName is a full name of the nested command, e.g. "remote add".
+func commandName(path []*Command) string {
+	names := make([]string, len(path))
+	for i, command := range path {
+		names[i] = command.Name
+	}
+
+	return strings.Join(names, " ")
+}
+
+func commandUsage(name string, command Command) string {
 	if command.Usage != "" {
-		return command.Name + " " + command.Usage
+		return name + " " + command.Usage
+	}
+
+	usage := name
+	if len(command.Commands) > 0 && !command.handled() {
+		return usage + " command [arguments]"
+	}
+
+	for _, flag := range command.flags() {
+		if flag.Required && flag.Default == "" {
+			usage += " " + flagUsage(flag, true)
+		} else {
+			usage += " [" + flagUsage(flag, true) + "]"
+		}
+	}
+
+	for _, argument := range command.Arguments {
+		usage += " " + argumentUsage(argument)
+	}
+
+	return usage
+}
+
+func argumentUsage(argument Argument) string {
+	usage := argument.Name
+	if argument.Variadic {
+		usage += "..."
 	}
 
-	usage := command.Name
-	for _, flag := range command.Flags {
-		usage += " [" + flagUsage(flag, true) + "]"
+	if argument.Optional {
+		return "[" + usage + "]"
 	}
 
 	return usage
@@ -104,29 +189,91 @@ func flagUsage(flag Flag, tiny bool) string {
 	}
 
 	usage := "--" + flag.Name
-	if flag.Variable {
+	switch {
+	case flag.Type == BoolFlag:
+	case flag.Type != StringFlag:
+		usage += "=" + flag.Type.String()
+	case flag.Variable:
 		usage += "=\"\""
 	}
 
 	return short + usage
 }
 
+// flagList lists the flags with their help, each flag
+// taking two lines.
+func flagList(flags []Flag, envPrefix string) string {
+	var list string
+	for _, flag := range flags {
+		list += "\n\t" + flagUsage(flag, false)
+		list += "\n\t\t" + alignMultilineHelp(flagHelp(flag, envPrefix))
+	}
+
+	return list
+}
+
+// flagHelp is a flag help followed by the notes on its value.
+func flagHelp(flag Flag, envPrefix string) string {
+	var notes []string
+	if flag.Default != "" && flag.isVariable() {
+		notes = append(notes, "default "+flag.Default)
+	}
+
+	if flag.Repeatable {
+		if flag.SplitCommas {
+			notes = append(notes, "comma-separated, may be repeated")
+		} else {
+			notes = append(notes, "may be repeated")
+		}
+	}
+
+	if env := flagEnv(flag, envPrefix); len(env) > 0 {
+		notes = append(notes, "env "+strings.Join(env, ", "))
+	}
+
+	if len(notes) == 0 {
+		return flag.Help
+	}
+
+	annotation := "(" + strings.Join(notes, "; ") + ")"
+	if flag.Help == "" {
+		return annotation
+	}
+
+	return flag.Help + " " + annotation
+}
+
 func (a *Application) globalHelp() string {
-	return templated(globalHelpTemplate, struct {
+	output := templated(globalHelpTemplate, struct {
 		Application
 		UngroupedCount int
+		Flags          []Flag
+		Topics         []Topic
 	}{
 		*a,
 		a.ungroupedCmdsCount,
+		a.globalFlags(nil),
+		a.topics(),
 	})
+
+	// TODO: Fix this nasty workaround for templating ASAP!
+	return strings.Replace(output, "\n\n\n", "", -1)
 }
 
-func (a *Application) commandHelp(command *Command) string {
+func (a *Application) commandHelp(path []*Command) string {
 	return templated(commandHelpTemplate, struct {
 		Command
-		App string
+		App       string
+		Path      string
+		Flags     []Flag
+		Globals   []Flag
+		EnvPrefix string
 	}{
-		*command,
+		*path[len(path)-1],
 		a.Name,
+		commandName(path),
+		path[len(path)-1].flags(),
+		a.globalFlags(path[:len(path)-1]),
+		a.EnvPrefix,
 	})
 }
@@ -0,0 +1,142 @@
+package climax
+
+import "runtime/debug"
+
+// Middleware wraps the handler of the command, e.g. to time it:
+//
+//	func timing(next climax.CmdHandler) climax.CmdHandler {
+//	    return func(ctx climax.Context) int {
+//	        defer func(start time.Time) {
+//	            ctx.Log(time.Since(start))
+//	        }(time.Now())
+//	        return next(ctx)
+//	    }
+//	}
+//
+// It can also pass a different context on with Context.WithContext.
+type Middleware func(next CmdHandler) CmdHandler
+
+// BeforeHook runs before the handler, once the command line is
+// parsed. It aborts the run by returning an error, otherwise it
+// may alter the context the handler receives.
+type BeforeHook func(ctx *Context) error
+
+// AfterHook runs after the handler and observes its exit code.
+// The error it returns fails the run.
+type AfterHook func(ctx Context, exitcode int) error
+
+// FinallyHook runs at the very end, even if the run is aborted
+// by a before hook, and observes the exit code.
+type FinallyHook func(ctx Context, exitcode int)
+
+// level is the set of hooks of the application or a command.
+type level struct {
+	middleware []Middleware
+	before     BeforeHook
+	after      AfterHook
+	finally    FinallyHook
+}
+
+// levels collects the hooks along the path, the application first.
+func (a *Application) levels(path []*Command) []level {
+	levels := []level{{a.Middleware, a.Before, a.After, a.Finally}}
+	for _, command := range path {
+		levels = append(levels, level{command.Middleware, command.Before, command.After, command.Finally})
+	}
+
+	return levels
+}
+
+// Use appends the middleware of the application, which wraps the
+// handlers of all the commands.
+func (a *Application) Use(middleware ...Middleware) {
+	a.Middleware = append(a.Middleware, middleware...)
+}
+
+// Use appends the middleware of the command, which wraps its
+// handler and the handlers of its subcommands.
+func (c *Command) Use(middleware ...Middleware) {
+	c.Middleware = append(c.Middleware, middleware...)
+}
+
+// execute runs the handler of the innermost command of the path
+// (or the handler given), wrapped by the middleware and hooks.
+//
+// For the path of app, remote and add, the order is:
+//
+//	app before, remote before, add before,
+//	app middleware, remote middleware, add middleware, handler,
+//	add after, add finally, remote after, remote finally,
+//	app after, app finally
+//
+// A failing before hook skips the rest of before hooks, the handler
+// and all the after hooks, but the finally hooks of the application
+// and the commands down to the failing one still run.
+func (a *Application) execute(path []*Command, ctx *Context, run func(Context) (int, error)) (int, error) {
+	levels := a.levels(path)
+
+	var failure error
+	handler := func(ctx Context) int {
+		exitcode, err := run(ctx)
+		if err != nil {
+			exitcode = a.exitCode(err)
+		}
+
+		failure = err
+		return exitcode
+	}
+
+	for i := len(levels) - 1; i >= 0; i-- {
+		middleware := levels[i].middleware
+		for j := len(middleware) - 1; j >= 0; j-- {
+			handler = middleware[j](handler)
+		}
+	}
+
+	exitcode, err := a.runLevels(levels, ctx, handler)
+	if err == nil {
+		err = failure
+	}
+
+	return exitcode, err
+}
+
+// runLevels runs the hooks of the levels around the handler. With
+// the panic recovery on, the panics are recovered at the innermost
+// level, so the finally hooks observe ExitPanic.
+func (a *Application) runLevels(levels []level, ctx *Context, handler CmdHandler) (exitcode int, err error) {
+	if len(levels) == 0 {
+		return handler(*ctx), nil
+	}
+
+	hooks := levels[0]
+	defer func() {
+		if a.recovering() {
+			if value := recover(); value != nil {
+				err = &PanicError{Value: value, Stack: debug.Stack()}
+				exitcode = a.exitCode(err)
+			}
+		}
+
+		if hooks.finally != nil {
+			hooks.finally(*ctx, exitcode)
+		}
+	}()
+
+	if hooks.before != nil {
+		if err := hooks.before(ctx); err != nil {
+			return a.exitCode(err), err
+		}
+	}
+
+	exitcode, err = a.runLevels(levels[1:], ctx, handler)
+	if err != nil || hooks.after == nil {
+		return exitcode, err
+	}
+
+	if err := hooks.after(*ctx, exitcode); err != nil {
+		return a.exitCode(err), err
+	}
+
+	return exitcode, nil
+}
@@ -0,0 +1,212 @@
+package climax
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type contextKey string
+
+func TestHooks(t *testing.T) {
+	var trace []string
+	record := func(format string, args ...interface{}) {
+		trace = append(trace, fmt.Sprintf(format, args...))
+	}
+
+	middleware := func(name string) Middleware {
+		return func(next CmdHandler) CmdHandler {
+			return func(ctx Context) int {
+				record("%s middleware", name)
+				return next(ctx.WithContext(context.WithValue(ctx.Context(), contextKey(name), true)))
+			}
+		}
+	}
+
+	hooks := func(name string) (BeforeHook, AfterHook, FinallyHook) {
+		before := func(ctx *Context) error {
+			record("%s before", name)
+			if ctx.Is("fail") && name == "remote" {
+				return errors.New("aborted")
+			}
+
+			return nil
+		}
+
+		after := func(ctx Context, exitcode int) error {
+			record("%s after %d", name, exitcode)
+			return nil
+		}
+
+		finally := func(ctx Context, exitcode int) {
+			record("%s finally %d", name, exitcode)
+		}
+
+		return before, after, finally
+	}
+
+	a := newTestApp("git")
+	a.AddFlag(Flag{Name: "fail"})
+	a.Use(middleware("git"))
+	a.Before, a.After, a.Finally = hooks("git")
+
+	remote := Command{Name: "remote"}
+	remote.Use(middleware("remote"))
+	remote.Before, remote.After, remote.Finally = hooks("remote")
+
+	add := Command{
+		Name: "add",
+		Handle: func(ctx Context) int {
+			var values []string
+			for _, name := range []string{"git", "remote", "add"} {
+				if ctx.Context().Value(contextKey(name)) != nil {
+					values = append(values, name)
+				}
+			}
+
+			record("handler sees %s", strings.Join(values, ","))
+			return 3
+		},
+	}
+	add.Use(middleware("add"))
+	add.Before, add.After, add.Finally = hooks("add")
+
+	remote.AddCommand(add)
+	a.AddCommand(remote)
+	defer output.Reset()
+
+	exitcode, err := a.RunArgs([]string{"remote", "add"})
+	if err != nil || exitcode != 3 {
+		t.Fatalf("run resulted in %d, %v", exitcode, err)
+	}
+
+	expected := []string{
+		"git before", "remote before", "add before",
+		"git middleware", "remote middleware", "add middleware",
+		"handler sees git,remote,add",
+		"add after 3", "add finally 3",
+		"remote after 3", "remote finally 3",
+		"git after 3", "git finally 3",
+	}
+
+	if !reflect.DeepEqual(trace, expected) {
+		t.Errorf("hooks ran in unexpected order:\n%s", strings.Join(trace, "\n"))
+	}
+
+	trace = nil
+	exitcode, err = a.RunArgs([]string{"--fail", "remote", "add"})
+	if err == nil || exitcode != 1 {
+		t.Errorf("aborted run resulted in %d, %v", exitcode, err)
+	}
+
+	expected = []string{"git before", "remote before", "remote finally 1", "git finally 1"}
+	if !reflect.DeepEqual(trace, expected) {
+		t.Errorf("aborted run ran unexpected hooks:\n%s", strings.Join(trace, "\n"))
+	}
+}
+
+func TestHooks_Errors(t *testing.T) {
+	a := newTestApp("git")
+	a.After = func(ctx Context, exitcode int) error {
+		if exitcode != 0 {
+			return &ExitError{Code: exitcode + 10}
+		}
+
+		return nil
+	}
+
+	a.AddCommand(Command{
+		Name: "push",
+		HandleErr: func(ctx Context) error {
+			return &ExitError{Code: 2, Err: errors.New("rejected")}
+		},
+	})
+	defer output.Reset()
+
+	exitcode, err := a.RunArgs([]string{"push"})
+	if exitcode != 12 || err.Error() != "exit status 12" {
+		t.Errorf("after hook error resulted in %d, %v", exitcode, err)
+	}
+
+	a.After = nil
+	exitcode, err = a.RunArgs([]string{"push"})
+	if exitcode != 2 || err.Error() != "rejected" {
+		t.Errorf("handler error resulted in %d, %v", exitcode, err)
+	}
+}
+
+func TestHooks_Version(t *testing.T) {
+	var trace []string
+	a := newTestApp("git")
+	a.Use(func(next CmdHandler) CmdHandler {
+		return func(ctx Context) int {
+			trace = append(trace, "middleware")
+			return next(ctx)
+		}
+	})
+	a.Before = func(ctx *Context) error {
+		trace = append(trace, "before")
+		return nil
+	}
+	a.After = func(ctx Context, exitcode int) error {
+		trace = append(trace, fmt.Sprintf("after %d", exitcode))
+		return nil
+	}
+	a.Finally = func(ctx Context, exitcode int) {
+		trace = append(trace, fmt.Sprintf("finally %d", exitcode))
+	}
+	a.AddCommand(Command{
+		Name: "version",
+		Handle: func(ctx Context) int {
+			trace = append(trace, "version")
+			return 0
+		},
+	})
+	defer output.Reset()
+
+	if _, err := a.RunArgs([]string{"version"}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"before", "middleware", "version", "after 0", "finally 0"}
+	if !reflect.DeepEqual(trace, expected) {
+		t.Errorf("version command ran unexpected hooks:\n%s", strings.Join(trace, "\n"))
+	}
+}
+
+func TestHooks_ExitCodeMapper(t *testing.T) {
+	var observed []int
+	a := newTestApp("git")
+	a.ExitCodeMapper = func(err error) int { return 42 }
+	a.Finally = func(ctx Context, exitcode int) {
+		observed = append(observed, exitcode)
+	}
+
+	a.AddCommand(Command{
+		Name: "push",
+		HandleErr: func(ctx Context) error {
+			return errors.New("rejected")
+		},
+	})
+	a.AddCommand(Command{
+		Name: "pull",
+		Before: func(ctx *Context) error {
+			return errors.New("offline")
+		},
+		Handle: func(ctx Context) int { return 0 },
+	})
+	defer output.Reset()
+
+	for _, name := range []string{"push", "pull"} {
+		if exitcode, _ := a.RunArgs([]string{name}); exitcode != 42 {
+			t.Errorf("%s resulted in exit code %d", name, exitcode)
+		}
+	}
+
+	if !reflect.DeepEqual(observed, []int{42, 42}) {
+		t.Errorf("finally hook observed exit codes %v", observed)
+	}
+}
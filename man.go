@@ -0,0 +1,282 @@
+package climax
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// manPage is a generated roff document along with its file name.
+type manPage struct {
+	File string
+	Text string
+}
+
+// ManPages writes roff man pages of the application into the
+// directory given, creating it if necessary.
+//
+// There is a page for the application itself (app.1), one for
+// every command, including the nested ones (app-remote-add.1),
+// and one for every help topic (app-topic.7). The pages are
+// cross-referenced in their SEE ALSO sections.
+func (a *Application) ManPages(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	for _, page := range a.manPages() {
+		path := filepath.Join(dir, page.File)
+		if err := ioutil.WriteFile(path, []byte(page.Text), 0644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// manPages generates all the pages of the application.
+func (a *Application) manPages() []manPage {
+	pages := []manPage{{a.Name + ".1", a.appManPage()}}
+
+	var walk func(path []*Command)
+	walk = func(path []*Command) {
+		pages = append(pages, manPage{manName(a.Name, path) + ".1", a.commandManPage(path)})
+
+		command := path[len(path)-1]
+		for i := range command.Commands {
+			walk(append(path[:len(path):len(path)], &command.Commands[i]))
+		}
+	}
+
+	for i := range a.Commands {
+		walk([]*Command{&a.Commands[i]})
+	}
+
+	for _, topic := range a.topics() {
+		pages = append(pages, manPage{a.Name + "-" + topic.Name + ".7", a.topicManPage(topic)})
+	}
+
+	return pages
+}
+
+// manName is a name of the command page, e.g. "git-remote-add".
+func manName(app string, path []*Command) string {
+	return app + "-" + strings.Replace(commandName(path), " ", "-", -1)
+}
+
+// roffEscape escapes the text so roff doesn't interpret it.
+func roffEscape(text string) string {
+	text = strings.Replace(text, `\`, `\e`, -1)
+	text = strings.Replace(text, "-", `\-`, -1)
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, ".") || strings.HasPrefix(line, "'") {
+			lines[i] = `\&` + line
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// roffQuote escapes the text and quotes it as the argument of
+// the request, doubling the quotes within.
+func roffQuote(text string) string {
+	return `"` + strings.Replace(roffEscape(text), `"`, `""`, -1) + `"`
+}
+
+// roffParagraphs turns blank-line separated paragraphs of the
+// text into roff ones, keeping the indented lines verbatim.
+func roffParagraphs(text string) string {
+	var b strings.Builder
+	for i, paragraph := range strings.Split(strings.TrimSpace(text), "\n\n") {
+		if i > 0 {
+			b.WriteString(".PP\n")
+		}
+
+		if strings.Contains(paragraph, "\n\t") || strings.HasPrefix(paragraph, "\t") {
+			fmt.Fprintf(&b, ".nf\n%s\n.fi\n", roffEscape(paragraph))
+		} else {
+			fmt.Fprintf(&b, "%s\n", roffEscape(paragraph))
+		}
+	}
+
+	return b.String()
+}
+
+func (a *Application) manHeader(b *strings.Builder, name string, section int, brief string) {
+	source := a.Name
+	if a.Version != "" {
+		source += " " + a.Version
+	}
+
+	fmt.Fprintf(b, ".TH %s %d \"\" %s %s\n",
+		roffQuote(strings.ToUpper(name)), section, roffQuote(source), roffQuote(a.Name+" manual"))
+
+	b.WriteString(".SH NAME\n")
+	if brief != "" {
+		fmt.Fprintf(b, "%s \\- %s\n", roffEscape(name), roffEscape(brief))
+	} else {
+		fmt.Fprintf(b, "%s\n", roffEscape(name))
+	}
+}
+
+// manFlags renders the OPTIONS section.
+func (a *Application) manFlags(b *strings.Builder, flags []Flag) {
+	if len(flags) == 0 {
+		return
+	}
+
+	b.WriteString(".SH OPTIONS\n")
+	for _, flag := range flags {
+		fmt.Fprintf(b, ".TP\n.B %s\n", roffEscape(flagUsage(flag, false)))
+		if help := flagHelp(flag, a.EnvPrefix); help != "" {
+			fmt.Fprintf(b, "%s\n", roffEscape(help))
+		}
+	}
+}
+
+// manSeeAlso renders the SEE ALSO section.
+func manSeeAlso(b *strings.Builder, references []string) {
+	if len(references) == 0 {
+		return
+	}
+
+	b.WriteString(".SH SEE ALSO\n")
+	b.WriteString(strings.Join(references, ",\n") + "\n")
+}
+
+func manReference(name string, section int) string {
+	return fmt.Sprintf("\\fB%s\\fR(%d)", roffEscape(name), section)
+}
+
+func (a *Application) appManPage() string {
+	var b strings.Builder
+	a.manHeader(&b, a.Name, 1, a.Brief)
+
+	b.WriteString(".SH SYNOPSIS\n")
+	fmt.Fprintf(&b, ".B %s\n", roffEscape(a.Name))
+	if len(a.Commands) > 0 {
+		b.WriteString("\\fIcommand\\fR [\\fIarguments\\fR]\n")
+	}
+
+	b.WriteString(".SH DESCRIPTION\n")
+	if a.Brief != "" {
+		fmt.Fprintf(&b, "%s\n", roffEscape(a.Brief))
+	}
+
+	if len(a.Commands) > 0 {
+		b.WriteString(".PP\nThe commands are:\n")
+		for _, command := range a.Commands {
+			fmt.Fprintf(&b, ".TP\n.B %s\n", roffEscape(command.Name))
+			if brief := aliased(command.Brief, command.Aliases); brief != "" {
+				fmt.Fprintf(&b, "%s\n", roffEscape(brief))
+			}
+		}
+	}
+
+	a.manFlags(&b, a.globalFlags(nil))
+
+	var references []string
+	for _, command := range a.Commands {
+		references = append(references, manReference(a.Name+"-"+command.Name, 1))
+	}
+
+	for _, topic := range a.topics() {
+		references = append(references, manReference(a.Name+"-"+topic.Name, 7))
+	}
+
+	manSeeAlso(&b, references)
+	return b.String()
+}
+
+func (a *Application) commandManPage(path []*Command) string {
+	command := path[len(path)-1]
+
+	var b strings.Builder
+	a.manHeader(&b, manName(a.Name, path), 1, command.Brief)
+
+	name := commandName(path)
+	usage := strings.TrimPrefix(commandUsage(name, *command), name)
+
+	b.WriteString(".SH SYNOPSIS\n")
+	fmt.Fprintf(&b, ".B %s %s\n", roffEscape(a.Name), roffEscape(name))
+	if usage = strings.TrimSpace(usage); usage != "" {
+		fmt.Fprintf(&b, "%s\n", roffEscape(usage))
+	}
+
+	b.WriteString(".SH DESCRIPTION\n")
+	switch {
+	case command.Help != "":
+		b.WriteString(roffParagraphs(command.Help))
+	case command.Brief != "":
+		fmt.Fprintf(&b, "%s\n", roffEscape(command.Brief))
+	}
+
+	if len(command.Aliases) > 0 {
+		fmt.Fprintf(&b, ".PP\nAliases: %s\n", roffEscape(strings.Join(command.Aliases, ", ")))
+	}
+
+	if len(command.Commands) > 0 {
+		b.WriteString(".PP\nThe subcommands are:\n")
+		for _, subcommand := range command.Commands {
+			fmt.Fprintf(&b, ".TP\n.B %s\n", roffEscape(subcommand.Name))
+			if brief := aliased(subcommand.Brief, subcommand.Aliases); brief != "" {
+				fmt.Fprintf(&b, "%s\n", roffEscape(brief))
+			}
+		}
+	}
+
+	if described(command.Arguments) {
+		b.WriteString(".SH ARGUMENTS\n")
+		for _, argument := range command.Arguments {
+			fmt.Fprintf(&b, ".TP\n.I %s\n", roffEscape(argument.Name))
+			if argument.Help != "" {
+				fmt.Fprintf(&b, "%s\n", roffEscape(argument.Help))
+			}
+		}
+	}
+
+	a.manFlags(&b, a.commandFlags(path))
+
+	if len(command.Examples) > 0 {
+		b.WriteString(".SH EXAMPLES\n")
+		for i, example := range command.Examples {
+			if i > 0 {
+				b.WriteString(".PP\n")
+			}
+
+			fmt.Fprintf(&b, ".nf\n$ %s %s %s\n.fi\n",
+				roffEscape(a.Name), roffEscape(name), roffEscape(example.Usecase))
+			if example.Description != "" {
+				fmt.Fprintf(&b, ".RS\n%s\n.RE\n", roffEscape(example.Description))
+			}
+		}
+	}
+
+	references := []string{manReference(a.Name, 1)}
+	if len(path) > 1 {
+		references = append(references, manReference(manName(a.Name, path[:len(path)-1]), 1))
+	}
+
+	for i := range command.Commands {
+		subpath := append(path[:len(path):len(path)], &command.Commands[i])
+		references = append(references, manReference(manName(a.Name, subpath), 1))
+	}
+
+	manSeeAlso(&b, references)
+	return b.String()
+}
+
+func (a *Application) topicManPage(topic Topic) string {
+	var b strings.Builder
+	a.manHeader(&b, a.Name+"-"+topic.Name, 7, topic.Brief)
+
+	b.WriteString(".SH DESCRIPTION\n")
+	b.WriteString(roffParagraphs(topic.Text))
+
+	manSeeAlso(&b, []string{manReference(a.Name, 1)})
+	return b.String()
+}
@@ -0,0 +1,64 @@
+package climax
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestManPages(t *testing.T) {
+	a := newGitApp()
+	a.Version = `2.0-rc "x"`
+	a.Commands[1].Commands[0].Flags = []Flag{{Name: "fetch", Short: "f", Help: "Fetch after adding."}}
+	a.Commands[1].Commands[1].Aliases = []string{"ls"}
+
+	dir := t.TempDir()
+	if err := a.ManPages(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string][]string{
+		"git.1": {
+			`.TH "GIT" 1 "" "git 2.0\-rc ""x""" "git manual"`,
+			"git \\- Git is a version control system.",
+			".SH SYNOPSIS\n.B git\n\\fIcommand\\fR [\\fIarguments\\fR]\n",
+			".TP\n.B \\-v, \\-\\-verbose\nLog verbosely.\n",
+			".SH SEE ALSO\n\\fBgit\\-init\\fR(1),\n\\fBgit\\-remote\\fR(1),\n\\fBgit\\-cat\\-file\\fR(1),\n\\fBgit\\-workflows\\fR(7)\n",
+		},
+		"git-remote.1": {
+			"git\\-remote \\- manage remotes",
+			".SH DESCRIPTION\nManage the set of tracked repositories.\n",
+			".TP\n.B list\nlists remotes (ls)\n",
+			"\\fBgit\\-remote\\-add\\fR(1),\n\\fBgit\\-remote\\-list\\fR(1)\n",
+		},
+		"git-remote-add.1": {
+			".SH SYNOPSIS\n.B git remote add\n[\\-f] name url\n",
+			".SH OPTIONS\n.TP\n.B \\-f, \\-\\-fetch\nFetch after adding.\n.TP\n.B \\-v, \\-\\-verbose\n",
+			".SH EXAMPLES\n.nf\n$ git remote add origin git://host/repo\n.fi\n.RS\nTracks origin.\n.RE\n",
+			".SH SEE ALSO\n\\fBgit\\fR(1),\n\\fBgit\\-remote\\fR(1)\n",
+		},
+		"git-workflows.7": {
+			`.TH "GIT\-WORKFLOWS" 7`,
+		},
+	}
+
+	for file, fragments := range expected {
+		page, err := ioutil.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+
+		for _, fragment := range fragments {
+			if !strings.Contains(string(page), fragment) {
+				t.Errorf("%s lacks %q:\n%s", file, fragment, page)
+			}
+		}
+	}
+
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 7 {
+		t.Errorf("%d pages were generated, expected 7", len(files))
+	}
+}
@@ -0,0 +1,119 @@
+package climax
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// defaultResponseDepth is used when ResponseFiles doesn't set one.
+const defaultResponseDepth = 8
+
+// ResponseFiles describes how the response files are read.
+//
+// The @path argument stands for the arguments read from the file,
+// which are split into words like POSIX shells do, so quoting is
+// needed for spaces. The arguments of the file may refer to other
+// response files as well. The @@ prefix escapes the literal @, so
+// @@path stands for the @path argument itself.
+//
+// The arguments are expanded before any parsing, so the response
+// files may hold options, as well as positional arguments. The
+// expansion stops at the first --, be it given or read from the
+// file, and the arguments after it are passed as they are.
+type ResponseFiles struct {
+	// Lines makes every non-empty line of the file a single
+	// argument, as is, instead of splitting the file into words.
+	Lines bool
+
+	// MaxDepth limits the nesting of response files, 8 if zero.
+	MaxDepth int
+}
+
+// ResponseFileError is returned when the response file can't
+// be read or expanded.
+type ResponseFileError struct {
+	Path string
+	Err  error
+}
+
+func (e *ResponseFileError) Error() string {
+	return fmt.Sprintf("response file %s: %s", e.Path, e.Err)
+}
+
+func (e *ResponseFileError) Unwrap() error {
+	return e.Err
+}
+
+// expandResponseFiles replaces the @path arguments with the
+// contents of the files, up to the first --.
+func (a *Application) expandResponseFiles(arguments []string, depth int) ([]string, error) {
+	if a.ResponseFiles == nil {
+		return arguments, nil
+	}
+
+	maxDepth := a.ResponseFiles.MaxDepth
+	if maxDepth == 0 {
+		maxDepth = defaultResponseDepth
+	}
+
+	var expanded []string
+	for i, argument := range arguments {
+		switch {
+		case argument == "--":
+			return append(expanded, arguments[i:]...), nil
+		case strings.HasPrefix(argument, "@@"):
+			expanded = append(expanded, argument[1:])
+			continue
+		case !strings.HasPrefix(argument, "@") || argument == "@":
+			expanded = append(expanded, argument)
+			continue
+		}
+
+		path := argument[1:]
+		if depth >= maxDepth {
+			return nil, &ResponseFileError{path, fmt.Errorf("nested deeper than %d files", maxDepth)}
+		}
+
+		words, err := a.readResponseFile(path)
+		if err != nil {
+			return nil, &ResponseFileError{path, err}
+		}
+
+		words, err = a.expandResponseFiles(words, depth+1)
+		if err != nil {
+			return nil, err
+		}
+
+		expanded = append(expanded, words...)
+
+		// The file ended the options, so must the rest of them.
+		for _, word := range words {
+			if word == "--" {
+				return append(expanded, arguments[i+1:]...), nil
+			}
+		}
+	}
+
+	return expanded, nil
+}
+
+func (a *Application) readResponseFile(path string) ([]string, error) {
+	data, err := ioutil.ReadFile(expandHome(path))
+	if err != nil {
+		return nil, err
+	}
+
+	if !a.ResponseFiles.Lines {
+		return splitWords(string(data))
+	}
+
+	var words []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSuffix(line, "\r"); line != "" {
+			words = append(words, line)
+		}
+	}
+
+	return words, nil
+}
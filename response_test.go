@@ -0,0 +1,120 @@
+package climax
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestResponseFiles(t *testing.T) {
+	var received Context
+	a := newTestApp("cc")
+	a.AddCommand(Command{
+		Name:  "build",
+		Flags: []Flag{{Name: "output", Short: "o", Variable: true}, {Name: "verbose"}},
+		Handle: func(ctx Context) int {
+			received = ctx
+			return 0
+		},
+	})
+	defer output.Reset()
+
+	dir := t.TempDir()
+	write := func(name, text string) string {
+		path := filepath.Join(dir, name)
+		ioutil.WriteFile(path, []byte(text), 0644)
+		return path
+	}
+
+	sources := write("sources.txt", "main.c 'my file.c'\n--verbose\n@"+filepath.Join(dir, "more.txt"))
+	write("more.txt", "util.c @@literal.c")
+	lines := write("lines.txt", "one file.c\r\n\n-o=out\n")
+
+	if _, err := a.RunArgs([]string{"build", "@" + sources}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(received.Args, []string{"@" + sources}) {
+		t.Errorf("response file was expanded without being enabled:\n%s", received)
+	}
+
+	a.ResponseFiles = &ResponseFiles{}
+
+	if _, err := a.RunArgs([]string{"build", "-o", "bin", "@" + sources, "@@x", "@"}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"main.c", "my file.c", "util.c", "@literal.c", "@x", "@"}
+	if !reflect.DeepEqual(received.Args, expected) || !received.Is("verbose") {
+		t.Errorf("response files resulted in:\n%s", received)
+	}
+
+	a.ResponseFiles.Lines = true
+	if _, err := a.RunArgs([]string{"build", "@" + lines}); err != nil {
+		t.Fatal(err)
+	}
+
+	if value, _ := received.Get("output"); value != "out" || !reflect.DeepEqual(received.Args, []string{"one file.c"}) {
+		t.Errorf("line response file resulted in:\n%s", received)
+	}
+
+	a.ResponseFiles.Lines = false
+	ended := write("ended.txt", "first.c -- @second.c")
+	if _, err := a.RunArgs([]string{"build", "@" + ended, "@third.c", "--", "@@x"}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected = []string{"first.c", "@second.c", "@third.c", "--", "@@x"}
+	if !reflect.DeepEqual(received.Args, expected) {
+		t.Errorf("response files after -- were expanded:\n%s", received)
+	}
+
+	if _, err := a.RunArgs([]string{"build", "--", "@" + ended}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(received.Args, []string{"@" + ended}) {
+		t.Errorf("response file after -- was expanded:\n%s", received)
+	}
+
+	recursive := write("recursive.txt", "@"+filepath.Join(dir, "recursive.txt"))
+	a.ResponseFiles = &ResponseFiles{MaxDepth: 3}
+
+	_, err := a.RunArgs([]string{"build", "@" + recursive})
+	if _, ok := err.(*ResponseFileError); !ok {
+		t.Errorf("recursive response file resulted in %v", err)
+	}
+
+	_, err = a.RunArgs([]string{"build", "@" + filepath.Join(dir, "missing.txt")})
+	if _, ok := err.(*ResponseFileError); !ok {
+		t.Errorf("missing response file resulted in %v", err)
+	}
+}
+
+func TestResponseFiles_Exec(t *testing.T) {
+	var tags []string
+	a := newTestApp("app")
+	a.Scripts = true
+	a.ResponseFiles = &ResponseFiles{}
+	a.AddFlag(Flag{Name: "tag", Variable: true})
+	a.AddCommand(Command{
+		Name: "run",
+		Handle: func(ctx Context) int {
+			tag, _ := ctx.Get("tag")
+			tags = append(tags, tag)
+			return 0
+		},
+	})
+	a.Stdin = strings.NewReader("run\nrun\n")
+	defer output.Reset()
+
+	if exitcode, err := a.RunArgs([]string{"--tag", "@@x", "exec"}); exitcode != 0 || err != nil {
+		t.Fatalf("exec resulted in %d, %v", exitcode, err)
+	}
+
+	if !reflect.DeepEqual(tags, []string{"@x", "@x"}) {
+		t.Errorf("global options were expanded again: %q", tags)
+	}
+}
@@ -0,0 +1,221 @@
+package climax
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+const execCommandName = "exec"
+
+// ScriptError is the failure of a line of the script.
+type ScriptError struct {
+	// Script is the name of the script, e.g. its path.
+	Script string
+
+	// Line is the number of the line, starting with 1.
+	Line int
+
+	Err error
+}
+
+func (e *ScriptError) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.Script, e.Line, e.Err)
+}
+
+func (e *ScriptError) Unwrap() error {
+	return e.Err
+}
+
+// ScriptLine is the outcome of a line of the script.
+type ScriptLine struct {
+	// Line is the number of the line, starting with 1. The lines
+	// joined by continuations are numbered after the first one.
+	Line int
+
+	// Text is the line as it's written in the script.
+	Text string
+
+	ExitCode int
+	Err      error
+}
+
+var assignment = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)=(.*)$`)
+
+// RunScript runs the commands of the script one after another,
+// as if each line was a separate command line, and returns the
+// outcomes of the lines run. The name of the script is used in
+// error messages, which are reported as they happen.
+//
+// Lines starting with # are comments, while the ones ending with
+// a backslash are continued on the next line. The line of the form
+// NAME=value sets the variable, and the $NAME or ${NAME} then stands
+// for its value, or the value of the environment variable, unless
+// it's in single quotes. Undefined variables fail the line.
+//
+// Unless told to keep going, the script stops at the first line
+// failing with non-zero exit code. It also stops once the context
+// is cancelled.
+func (a *Application) RunScript(ctx context.Context, name string, script io.Reader, keepGoing bool) ([]ScriptLine, error) {
+	return a.runScript(ctx, name, script, keepGoing, nil)
+}
+
+// runScript runs the script, the lines of which are run with the
+// global options given.
+func (a *Application) runScript(ctx context.Context, name string, script io.Reader, keepGoing bool, options []string) ([]ScriptLine, error) {
+	variables := make(map[string]string)
+	expand := func(variable string) (string, error) {
+		if value, ok := variables[variable]; ok {
+			return value, nil
+		}
+
+		if value, ok := os.LookupEnv(variable); ok {
+			return value, nil
+		}
+
+		return "", fmt.Errorf("variable %s is not defined", variable)
+	}
+
+	var (
+		lines   []ScriptLine
+		pending []string
+		first   int
+	)
+
+	scanner := bufio.NewScanner(script)
+	for number := 1; scanner.Scan(); number++ {
+		text := scanner.Text()
+
+		if len(pending) == 0 {
+			first = number
+			if trimmed := strings.TrimSpace(text); trimmed == "" || strings.HasPrefix(trimmed, "#") {
+				continue
+			}
+		}
+
+		// The line continues if it ends with the backslash which
+		// isn't escaped itself, i.e. with the odd number of them.
+		if trailing := len(text) - len(strings.TrimRight(text, `\`)); trailing%2 == 1 {
+			pending = append(pending, strings.TrimSuffix(text, `\`))
+			continue
+		}
+
+		line := ScriptLine{Line: first, Text: strings.Join(append(pending, text), "")}
+		pending = nil
+
+		if match := assignment.FindStringSubmatch(line.Text); match != nil {
+			words, err := expandWords(match[2], expand)
+			if err == nil {
+				variables[match[1]] = strings.Join(words, " ")
+				continue
+			}
+
+			line.Err = err
+		} else {
+			line.ExitCode, line.Err = a.runScriptLine(ctx, line.Text, expand, options)
+		}
+
+		if line.Err != nil {
+			line.Err = &ScriptError{name, line.Line, line.Err}
+			if line.ExitCode == 0 {
+				line.ExitCode = a.exitCode(line.Err)
+			}
+
+			a.reportError(line.Err)
+		}
+
+		lines = append(lines, line)
+		if line.ExitCode != 0 && !keepGoing || ctx.Err() != nil {
+			break
+		}
+	}
+
+	if len(pending) > 0 {
+		line := ScriptLine{Line: first, Text: strings.Join(pending, "")}
+		line.Err = &ScriptError{name, first, errors.New("unterminated continuation")}
+		line.ExitCode = a.exitCode(line.Err)
+
+		a.reportError(line.Err)
+		lines = append(lines, line)
+	}
+
+	return lines, scanner.Err()
+}
+
+func (a *Application) runScriptLine(ctx context.Context, text string, expand func(string) (string, error), options []string) (int, error) {
+	words, err := expandWords(text, expand)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(words) > 0 {
+		name, _ := a.resolveCommand(a.Commands, words[0], a.builtins()...)
+		if a.commandByName(name) == nil && (name == execCommandName || name == shellCommandName) {
+			return 0, fmt.Errorf("%s can't be used in scripts", name)
+		}
+	}
+
+	return a.runContext(ctx, options, words)
+}
+
+// runExec runs the built-in exec command:
+//
+//	$ app [options] exec [--keep-going] [script]
+//
+// The script is read from the standard input if it's missing or -.
+// The global options given before exec apply to every line.
+func (a *Application) runExec(ctx context.Context, options, arguments []string) (int, error) {
+	keepGoing := Flag{
+		Name:  "keep-going",
+		Short: "k",
+		Help:  "Run the rest of the script after a line fails.",
+	}
+
+	// The option of the built-in comes from the command line alone,
+	// neither the environment nor the config know it.
+	context, err := a.parseArgs([]Flag{keepGoing}, arguments)
+	if err != nil {
+		return 1, err
+	}
+
+	err = checkArguments([]Argument{{Name: "script", Optional: true}}, context.Args)
+	if err != nil {
+		return 1, err
+	}
+
+	name, script := "stdin", a.stdin()
+	if len(context.Args) > 0 && context.Args[0] != "-" {
+		file, err := os.Open(expandHome(context.Args[0]))
+		if err != nil {
+			return 1, err
+		}
+		defer file.Close()
+
+		name, script = context.Args[0], file
+	}
+
+	lines, err := a.runScript(ctx, name, script, context.Is("keep-going"), options)
+	if err != nil {
+		return 1, err
+	}
+
+	exitcode := 0
+	fmt.Fprintf(a.stderr(), "\nline  exit  command\n")
+	for _, line := range lines {
+		fmt.Fprintf(a.stderr(), "%4d  %4d  %s\n", line.Line, line.ExitCode, line.Text)
+		if exitcode == 0 {
+			exitcode = line.ExitCode
+		}
+	}
+
+	if ctx.Err() != nil {
+		return ExitInterrupted, nil
+	}
+
+	return exitcode, nil
+}
@@ -0,0 +1,193 @@
+package climax
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testScript string = `# set up the remotes
+HOST=git.example.com
+NAME="my origin"
+
+remote add "$NAME" \
+	git://${HOST}/repo
+remote add '$NAME' $CLIMAX_TEST_PATH
+fail 4
+remote add upstream $UNDEFINED
+remote list
+`
+
+func TestRunScript(t *testing.T) {
+	var handled string
+	var added []string
+	a := newNestedApp(&handled)
+	a.Scripts = true
+	a.Commands[1].Commands[0].Handle = func(ctx Context) int {
+		added = append(added, strings.Join(ctx.Args, "|"))
+		return 0
+	}
+	a.AddCommand(Command{
+		Name: "fail",
+		Handle: func(ctx Context) int {
+			code, _ := parseInt(ctx.Args[0])
+			return code
+		},
+	})
+	defer output.Reset()
+
+	os.Setenv("CLIMAX_TEST_PATH", "/srv/repo")
+	defer os.Unsetenv("CLIMAX_TEST_PATH")
+
+	lines, err := a.RunScript(context.Background(), "steps.txt", strings.NewReader(testScript), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "my origin|git://git.example.com/repo,$NAME|/srv/repo"
+	if strings.Join(added, ",") != expected {
+		t.Errorf("script ran %q, expected %q", added, expected)
+	}
+
+	var summary []string
+	for _, line := range lines {
+		summary = append(summary, fmt.Sprintf("%d:%d", line.Line, line.ExitCode))
+	}
+
+	if strings.Join(summary, " ") != "5:0 7:0 8:4 9:1 10:0" {
+		t.Errorf("script resulted in %q", summary)
+	}
+
+	if message := "git: steps.txt:9: variable UNDEFINED is not defined\n"; output.String() != message {
+		t.Errorf("script reported %q, expected %q", output.String(), message)
+	}
+}
+
+func TestRunScript_Continuation(t *testing.T) {
+	var handled string
+	var added []string
+	a := newNestedApp(&handled)
+	a.Scripts = true
+	a.Commands[1].Commands[0].Handle = func(ctx Context) int {
+		added = append(added, strings.Join(ctx.Args, "|"))
+		return 0
+	}
+	defer output.Reset()
+
+	script := "remote add first git://x\nremote add \\\n"
+	lines, err := a.RunScript(context.Background(), "steps.txt", strings.NewReader(script), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(lines) != 2 || lines[1].Line != 2 || lines[1].ExitCode != 1 {
+		t.Errorf("unterminated continuation resulted in %+v", lines)
+	}
+
+	if message := "git: steps.txt:2: unterminated continuation\n"; output.String() != message {
+		t.Errorf("script reported %q, expected %q", output.String(), message)
+	}
+
+	added = nil
+	script = "remote add x\\\\\\\ny z\\\\\n"
+	if _, err := a.RunScript(context.Background(), "steps.txt", strings.NewReader(script), false); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := `x\y|z\`; strings.Join(added, ",") != expected {
+		t.Errorf("escaped backslashes resulted in %q, expected %q", added, expected)
+	}
+}
+
+func TestRunArgs_Exec(t *testing.T) {
+	var handled string
+	var added []string
+	a := newNestedApp(&handled)
+	a.Scripts = true
+	a.Commands[1].Commands[0].Handle = func(ctx Context) int {
+		added = append(added, strings.Join(ctx.Args, "|"))
+		return 0
+	}
+	a.AddCommand(Command{
+		Name: "fail",
+		Handle: func(ctx Context) int {
+			code, _ := parseInt(ctx.Args[0])
+			return code
+		},
+	})
+	defer output.Reset()
+
+	path := filepath.Join(t.TempDir(), "steps.txt")
+	ioutil.WriteFile(path, []byte("remote add a x\nfail 3\nremote add b y\n"), 0644)
+
+	exitcode, err := a.RunArgs([]string{"exec", path})
+	if err != nil || exitcode != 3 || len(added) != 1 {
+		t.Errorf("exec resulted in %d, %v, ran %q", exitcode, err, added)
+	}
+
+	summary := "\nline  exit  command\n   1     0  remote add a x\n   2     3  fail 3\n"
+	if output.String() != summary {
+		t.Errorf("exec summary is %q, expected %q", output.String(), summary)
+	}
+
+	output.Reset()
+	a.Stdin = strings.NewReader("remote add c z\nfail 5\nfail 6\nremote add d w\nexec -\n")
+	exitcode, _ = a.RunArgs([]string{"exec", "--keep-going"})
+	if exitcode != 5 || len(added) != 3 {
+		t.Errorf("exec --keep-going resulted in %d, ran %q", exitcode, added)
+	}
+
+	if !strings.Contains(output.String(), "git: stdin:5: exec can't be used in scripts\n") {
+		t.Errorf("nested exec wasn't rejected:\n%s", output.String())
+	}
+
+	output.Reset()
+	a.EnvPrefix = "CLIMAX_TEST"
+	os.Setenv("CLIMAX_TEST_KEEP_GOING", "1")
+	defer os.Unsetenv("CLIMAX_TEST_KEEP_GOING")
+
+	a.Stdin = strings.NewReader("fail 7\nremote add e v\n")
+	exitcode, _ = a.RunArgs([]string{"exec"})
+	if exitcode != 7 || len(added) != 3 {
+		t.Errorf("exec kept going by the environment: %d, ran %q", exitcode, added)
+	}
+
+	if _, err := a.RunArgs([]string{"exec", "missing.txt"}); err == nil {
+		t.Errorf("missing script didn't fail")
+	}
+}
+
+func TestRunArgs_ExecOptions(t *testing.T) {
+	var verbose []bool
+	a := newTestApp("app")
+	a.Scripts = true
+	a.Shell = &Shell{}
+	a.AddFlag(Flag{Name: "verbose", Short: "v"})
+	a.AddCommand(Command{
+		Name: "run",
+		Handle: func(ctx Context) int {
+			verbose = append(verbose, ctx.Is("verbose"))
+			return 0
+		},
+	})
+	defer output.Reset()
+
+	a.Stdin = strings.NewReader("run\nrun\n")
+	if _, err := a.RunArgs([]string{"-v", "exec"}); err != nil {
+		t.Fatal(err)
+	}
+
+	a.Stdin = strings.NewReader("run\n")
+	if _, err := a.RunArgs([]string{"-v", "shell"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(verbose, []bool{true, true, true}) {
+		t.Errorf("global options didn't reach the lines: %v", verbose)
+	}
+}
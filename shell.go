@@ -0,0 +1,296 @@
+package climax
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// Shell describes the interactive shell of the application,
+// which runs commands one line after another in the same process.
+//
+// Lines are split into words like POSIX shells do, and then run
+// the same way as the command line is. Besides the commands of
+// the application, the shell understands the exit (also quit)
+// and history builtins.
+//
+// On the terminal, the shell edits the line itself: tab completes
+// the word being typed, listing the candidates if there are several,
+// up and down arrows walk the history, ^U clears the line, ^C drops
+// it and ^D on the empty line exits. Where the terminal can't be put
+// into raw mode, or the input is no terminal, end the line with a tab
+// and press enter to list the candidates instead of running the line.
+type Shell struct {
+	// Prompt is printed before every line, "app> " by default.
+	Prompt string
+
+	// History is the file the lines are appended to, so they are
+	// kept between the sessions. The leading ~ stands for the home
+	// directory of the user. Empty path disables the history.
+	//
+	// Example: ~/.camus_history
+	History string
+}
+
+const shellCommandName = "shell"
+
+// RunShell runs the interactive shell until the input ends or the
+// exit builtin is used, and returns the exit code of the shell.
+// The commands run with the context given, once it's cancelled,
+// the shell ends too.
+//
+// The shell handles SIGINT and SIGTERM itself while it runs: the
+// signal cancels the context of the command being run, and the
+// shell goes on with the next line. SIGINT at the prompt starts
+// a new one, while SIGTERM exits.
+//
+// The commands share the standard input with the shell, reading
+// right after the line that runs them.
+//
+// It can be used as the default handler, so the application goes
+// interactive when run without arguments:
+//
+//	app.Default = func(ctx climax.Context) int {
+//	    return app.RunShell(ctx.Context())
+//	}
+func (a *Application) RunShell(ctx context.Context) int {
+	return a.runShell(ctx, nil)
+}
+
+// runShell runs the shell, the lines of which are run with the
+// global options given.
+func (a *Application) runShell(ctx context.Context, options []string) int {
+	shell := a.Shell
+	if shell == nil {
+		shell = &Shell{}
+	}
+
+	prompt := shell.Prompt
+	if prompt == "" {
+		prompt = a.Name + "> "
+	}
+
+	history := a.readHistory(shell.History)
+
+	// Every line gets its own context, which the signals cancel,
+	// so interrupting the command doesn't end the shell.
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	if i, ok := ctx.Value(interruptionKey{}).(*interruption); ok {
+		i.hold()
+		defer i.release()
+	}
+
+	// The terminal, if any, is put into raw mode for editing.
+	terminal, _ := a.stdin().(*os.File)
+
+	reader, previous := bufio.NewReader(a.stdin()), a.input
+	a.input = reader
+	defer func() { a.input = previous }()
+
+	for {
+		line, err := a.readLine(reader, terminal, signals, prompt, history)
+		if err != nil && line == "" {
+			a.println()
+			return 0
+		}
+
+		if ctx.Err() != nil {
+			return ExitInterrupted
+		}
+
+		if strings.HasSuffix(line, "\t") {
+			a.completeLine(strings.TrimSuffix(line, "\t"))
+			continue
+		}
+
+		words, err := splitWords(line)
+		if err != nil {
+			a.reportError(err)
+			continue
+		}
+
+		if len(words) == 0 {
+			continue
+		}
+
+		history = append(history, line)
+		a.appendHistory(shell.History, line)
+
+		// The commands of the application take precedence over
+		// the ones of the shell.
+		if a.commandByName(words[0]) == nil {
+			switch words[0] {
+			case "exit", "quit":
+				if len(words) == 1 {
+					return 0
+				}
+
+				exitcode, err := strconv.Atoi(words[1])
+				if err != nil || len(words) > 2 {
+					a.reportError(&ArgumentError{"code", fmt.Sprintf("has invalid value %q", strings.Join(words[1:], " "))})
+					continue
+				}
+
+				return exitcode
+
+			case "history":
+				for i, each := range history {
+					a.printf("%5d  %s\n", i+1, each)
+				}
+
+				continue
+			}
+		}
+
+		if name, _ := a.resolveCommand(a.Commands, words[0], a.builtins()...); name == shellCommandName && a.commandByName(name) == nil {
+			a.reportError(fmt.Errorf("already in the shell"))
+			continue
+		}
+
+		lineCtx, cancel := context.WithCancel(ctx)
+		interruption := a.interruptOn(signals, cancel)
+
+		_, err = a.runContext(lineCtx, options, words)
+		if err != nil && !(interruption.interrupted() && errors.Is(err, context.Canceled)) {
+			a.reportError(err)
+		}
+
+		interruption.finish()
+
+		if ctx.Err() != nil {
+			return ExitInterrupted
+		}
+	}
+}
+
+// readLine prints the prompt and reads the next line of the input,
+// editing it with lineEditor if the input is a terminal. SIGINT at the prompt prints
+// it anew, as the terminal drops the line being typed, while SIGTERM
+// exits, since there's no command to cancel.
+func (a *Application) readLine(reader *bufio.Reader, terminal *os.File, signals chan os.Signal, prompt string, history []string) (string, error) {
+	raw, restore := false, func() {}
+	if terminal != nil {
+		if restoreMode, err := makeRaw(terminal.Fd()); err == nil {
+			raw, restore = true, restoreMode
+		}
+	}
+
+	done, finished := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(finished)
+		for {
+			select {
+			case received := <-signals:
+				if received == syscall.SIGTERM {
+					restore()
+					exit(ExitInterrupted)
+					return
+				}
+
+				a.printf("\n%s", prompt)
+
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	defer func() {
+		close(done)
+		<-finished
+		restore()
+	}()
+
+	if raw {
+		return newLineEditor(a, prompt, history).edit(reader)
+	}
+
+	a.printf("%s", prompt)
+
+	line, err := reader.ReadString('\n')
+	line = strings.TrimSuffix(line, "\n")
+	return strings.TrimSuffix(line, "\r"), err
+}
+
+// reportError prints the error the way Run does.
+func (a *Application) reportError(err error) {
+	if message := a.formatError(err); message != "" {
+		fmt.Fprintln(a.stderr(), message)
+	}
+}
+
+// completeLine lists the candidates completing the last word of
+// the line.
+func (a *Application) completeLine(line string) {
+	words, err := splitWords(line)
+	if err != nil {
+		a.reportError(err)
+		return
+	}
+
+	if line == "" || strings.HasSuffix(line, " ") {
+		words = append(words, "")
+	}
+
+	for _, candidate := range a.lineCandidates(words) {
+		if candidate.Description != "" {
+			a.printf("%-11s %s\n", candidate.Value, candidate.Description)
+		} else {
+			a.println(candidate.Value)
+		}
+	}
+}
+
+// lineCandidates completes the words typed into the shell, with
+// the builtins of the shell at the start of the line.
+func (a *Application) lineCandidates(words []string) []Candidate {
+	candidates := a.Complete(words)
+	if len(words) != 1 {
+		return candidates
+	}
+
+	builtins := []Candidate{
+		{"exit", "leave the shell"},
+		{"quit", "leave the shell"},
+		{"history", "list the lines run before"},
+	}
+
+	return append(candidates, filterCandidates(builtins, words[0])...)
+}
+
+func (a *Application) readHistory(path string) []string {
+	if path == "" {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(expandHome(path))
+	if err != nil || len(data) == 0 {
+		return nil
+	}
+
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+func (a *Application) appendHistory(path, line string) {
+	if path == "" {
+		return
+	}
+
+	file, err := os.OpenFile(expandHome(path), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	fmt.Fprintln(file, line)
+}
@@ -0,0 +1,190 @@
+package climax
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestShell(t *testing.T) {
+	var handled string
+	a := newNestedApp(&handled)
+
+	history := filepath.Join(t.TempDir(), "history")
+	ioutil.WriteFile(history, []byte("init\n"), 0600)
+	a.Shell = &Shell{History: history}
+
+	var added []string
+	a.Commands[1].Commands[0].Handle = func(ctx Context) int {
+		added = append(added, strings.Join(ctx.Args, "|"))
+		return 0
+	}
+
+	a.Stdin = strings.NewReader(strings.Join([]string{
+		`remote add "my origin" 'git://x'`,
+		"",
+		"remote add alone",
+		"rem\t",
+		"history",
+		"exit 3",
+		"remote add never run",
+	}, "\n"))
+	defer output.Reset()
+
+	exitcode, err := a.RunArgs([]string{"shell"})
+	if err != nil || exitcode != 3 {
+		t.Errorf("shell resulted in %d, %v", exitcode, err)
+	}
+
+	if strings.Join(added, ",") != "my origin|git://x" {
+		t.Errorf("shell ran unexpected commands: %q", added)
+	}
+
+	for _, fragment := range []string{
+		"git> git> git> git: argument url is missing\n",
+		"git> remote      manage remotes\ngit> ",
+		"    1  init\n    2  remote add \"my origin\" 'git://x'\n    3  remote add alone\n    4  history\n",
+	} {
+		if !strings.Contains(output.String(), fragment) {
+			t.Errorf("shell output lacks %q:\n%s", fragment, output.String())
+		}
+	}
+
+	saved, _ := ioutil.ReadFile(history)
+	if !strings.HasSuffix(string(saved), "history\nexit 3\n") {
+		t.Errorf("history file is not kept:\n%s", saved)
+	}
+}
+
+func TestShell_Builtins(t *testing.T) {
+	a := newTestApp("app")
+	a.Stdin = strings.NewReader("e\t\nhelp\n'unterminated\nshell\nquit\n")
+	defer output.Reset()
+
+	if exitcode := a.RunShell(context.Background()); exitcode != 0 {
+		t.Errorf("quit resulted in exit code %d", exitcode)
+	}
+
+	for _, fragment := range []string{
+		"app> exit        leave the shell\n",
+		"unterminated quote",
+		"already in the shell",
+	} {
+		if !strings.Contains(output.String(), fragment) {
+			t.Errorf("shell output lacks %q:\n%s", fragment, output.String())
+		}
+	}
+
+	output.Reset()
+	a.Stdin = strings.NewReader("help\n")
+	if exitcode := a.RunShell(context.Background()); exitcode != 0 || !strings.HasSuffix(output.String(), "app> \n") {
+		t.Errorf("end of input resulted in %d:\n%s", exitcode, output.String())
+	}
+
+	if _, err := a.RunArgs([]string{"shell"}); err == nil {
+		t.Errorf("shell command is available without Shell")
+	}
+}
+
+func TestShell_Stdin(t *testing.T) {
+	var handled string
+	var added []string
+	a := newNestedApp(&handled)
+	a.Scripts = true
+	a.Shell = &Shell{}
+	a.Commands[1].Commands[0].Handle = func(ctx Context) int {
+		added = append(added, strings.Join(ctx.Args, "|"))
+		return 0
+	}
+	a.Stdin = strings.NewReader("exec\nremote add one git://one\nremote add two git://two")
+	defer output.Reset()
+
+	if exitcode := a.RunShell(context.Background()); exitcode != 0 {
+		t.Errorf("shell resulted in exit code %d", exitcode)
+	}
+
+	if strings.Join(added, ",") != "one|git://one,two|git://two" {
+		t.Errorf("exec didn't read the rest of the input: %q", added)
+	}
+
+	if !strings.Contains(output.String(), "   1     0  remote add one git://one\n") {
+		t.Errorf("exec didn't run the script:\n%s", output.String())
+	}
+}
+
+func TestShell_Resolution(t *testing.T) {
+	var ran []string
+	a := newTestApp("app")
+	a.Shell = &Shell{}
+	a.Scripts = true
+	a.PrefixMatching = true
+	for _, name := range []string{"history", "quit"} {
+		name := name
+		a.AddCommand(Command{
+			Name: name,
+			Handle: func(ctx Context) int {
+				ran = append(ran, name)
+				return 0
+			},
+		})
+	}
+
+	a.Stdin = strings.NewReader("sh\nhistory\nquit\nexec\ne\nexit\n")
+	defer output.Reset()
+
+	if exitcode := a.RunShell(context.Background()); exitcode != 0 {
+		t.Errorf("shell resulted in exit code %d", exitcode)
+	}
+
+	if strings.Join(ran, ",") != "history,quit" {
+		t.Errorf("shell ran %q instead of the commands", ran)
+	}
+
+	for _, fragment := range []string{
+		"app: already in the shell\n",
+		"app: stdin:1: exec can't be used in scripts\n",
+	} {
+		if !strings.Contains(output.String(), fragment) {
+			t.Errorf("shell output lacks %q:\n%s", fragment, output.String())
+		}
+	}
+}
+
+func TestLineEditor(t *testing.T) {
+	var handled string
+	a := newNestedApp(&handled)
+	defer output.Reset()
+
+	cases := []struct {
+		keys, line string
+	}{
+		{"rem\ta\t'my origin' x\r", "remote add 'my origin' x"},
+		{"remote add --\x7f\x7fo\r", "remote add o"},
+		{"ab\x15in\t\r", "init "},
+		{"x\x03\x1b[A\x1b[A\x1b[B\r", "remote add"},
+		{"\x1b[A\x1b[Bhelp\x1b[C\n", "help"},
+	}
+
+	for _, c := range cases {
+		editor := newLineEditor(a, "git> ", []string{"init", "remote add"})
+		line, err := editor.edit(bufio.NewReader(strings.NewReader(c.keys)))
+		if err != nil || line != c.line {
+			t.Errorf("%q resulted in %q, %v, expected %q", c.keys, line, err, c.line)
+		}
+	}
+
+	output.Reset()
+	editor := newLineEditor(a, "git> ", nil)
+	line, err := editor.edit(bufio.NewReader(strings.NewReader("\t\x04")))
+	if err != io.EOF || line != "" {
+		t.Errorf("^D resulted in %q, %v", line, err)
+	}
+
+	if !strings.Contains(output.String(), "\nremote      manage remotes\n") {
+		t.Errorf("editor didn't list the candidates:\n%q", output.String())
+	}
+}
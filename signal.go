@@ -0,0 +1,144 @@
+package climax
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// ExitInterrupted is the exit code of the runs interrupted by
+// SIGINT or SIGTERM, as shells report the processes killed by
+// SIGINT.
+const ExitInterrupted = 130
+
+const timeoutFlagName = "timeout"
+
+// exit is os.Exit, unless the tests replace it.
+var exit = os.Exit
+
+// timeoutFlag is the option limiting the run time of the command.
+func timeoutFlag() Flag {
+	return Flag{
+		Name: timeoutFlagName,
+		Type: DurationFlag,
+		Help: "Cancel the command after the time given, e.g. 30s.",
+	}
+}
+
+// flags returns the options of the command, including the
+// generated ones.
+func (c *Command) flags() []Flag {
+	if !c.TimeoutFlag || flagByName(&c.Flags, timeoutFlagName) != nil {
+		return c.Flags
+	}
+
+	flags := append([]Flag{}, c.Flags...)
+	return append(flags, timeoutFlag())
+}
+
+// bindContext attaches the parent context to the command context,
+// limited by the --timeout option if the command has one. Call the
+// returned function once the command is done.
+func bindContext(parent context.Context, ctx *Context, command *Command) context.CancelFunc {
+	if command != nil && command.TimeoutFlag {
+		if timeout, ok := ctx.GetDuration(timeoutFlagName); ok && timeout > 0 {
+			var cancel context.CancelFunc
+			ctx.ctx, cancel = context.WithTimeout(parent, timeout)
+			return cancel
+		}
+	}
+
+	ctx.ctx = parent
+	return func() {}
+}
+
+// interruption cancels the context on SIGINT or SIGTERM and
+// forces the exit on the second signal or after the grace period.
+type interruption struct {
+	signals   chan os.Signal
+	done      chan struct{}
+	cancel    context.CancelFunc
+	signalled int32
+}
+
+// interruptionKey is the context key of the interruption of Run,
+// so the shell can take the signals over from it.
+type interruptionKey struct{}
+
+func (a *Application) notifySignals() (context.Context, *interruption) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	i := a.interruptOn(signals, cancel)
+	return context.WithValue(ctx, interruptionKey{}, i), i
+}
+
+// interruptOn calls cancel once a signal arrives on the channel.
+func (a *Application) interruptOn(signals chan os.Signal, cancel context.CancelFunc) *interruption {
+	i := &interruption{
+		signals: signals,
+		done:    make(chan struct{}),
+		cancel:  cancel,
+	}
+
+	go i.wait(a.GracePeriod)
+	return i
+}
+
+func (i *interruption) wait(grace time.Duration) {
+	select {
+	case <-i.signals:
+	case <-i.done:
+		return
+	}
+
+	atomic.StoreInt32(&i.signalled, 1)
+	i.cancel()
+
+	var deadline <-chan time.Time
+	if grace > 0 {
+		timer := time.NewTimer(grace)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	select {
+	case <-i.signals:
+	case <-deadline:
+	case <-i.done:
+		return
+	}
+
+	exit(ExitInterrupted)
+}
+
+// interrupted tells whether a signal has arrived.
+func (i *interruption) interrupted() bool {
+	return atomic.LoadInt32(&i.signalled) == 1
+}
+
+// hold stops the delivery of the signals, until release.
+func (i *interruption) hold() {
+	signal.Stop(i.signals)
+}
+
+func (i *interruption) release() {
+	signal.Notify(i.signals, os.Interrupt, syscall.SIGTERM)
+}
+
+// finish stops waiting for the signals.
+func (i *interruption) finish() {
+	close(i.done)
+	i.cancel()
+}
+
+// stop restores the default handling of the signals.
+func (i *interruption) stop() {
+	signal.Stop(i.signals)
+	i.finish()
+}
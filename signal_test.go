@@ -0,0 +1,147 @@
+//go:build !windows
+// +build !windows
+
+package climax
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRun_Interrupted(t *testing.T) {
+	a := newTestApp("sleep")
+	a.AddCommand(Command{
+		Name: "wait",
+		HandleErr: func(ctx Context) error {
+			syscall.Kill(os.Getpid(), syscall.SIGINT)
+			<-ctx.Context().Done()
+			return ctx.Context().Err()
+		},
+	})
+	defer output.Reset()
+
+	setArguments("wait")
+	if exitcode := a.Run(); exitcode != ExitInterrupted {
+		t.Errorf("interrupted run resulted in exit code %d", exitcode)
+	}
+
+	if output.String() != "" {
+		t.Errorf("interrupted run reported %q", output.String())
+	}
+}
+
+func TestRun_GracePeriod(t *testing.T) {
+	forced := make(chan int, 1)
+	exit = func(code int) { forced <- code }
+	defer func() { exit = os.Exit }()
+
+	a := newTestApp("sleep")
+	a.GracePeriod = 10 * time.Millisecond
+	a.AddCommand(Command{
+		Name: "ignore",
+		Handle: func(ctx Context) int {
+			syscall.Kill(os.Getpid(), syscall.SIGTERM)
+			select {
+			case code := <-forced:
+				return code
+			case <-time.After(5 * time.Second):
+				return 0
+			}
+		},
+	})
+	defer output.Reset()
+
+	setArguments("ignore")
+	if exitcode := a.Run(); exitcode != ExitInterrupted {
+		t.Errorf("grace period didn't force the exit: %d", exitcode)
+	}
+}
+
+func TestRunContext_Timeout(t *testing.T) {
+	var received Context
+	a := newTestApp("sleep")
+	a.AddCommand(Command{
+		Name:        "wait",
+		TimeoutFlag: true,
+		HandleErr: func(ctx Context) error {
+			received = ctx
+			<-ctx.Context().Done()
+			return ctx.Context().Err()
+		},
+	})
+	defer output.Reset()
+
+	exitcode, err := a.RunArgs([]string{"wait", "--timeout=10ms"})
+	if !errors.Is(err, context.DeadlineExceeded) || exitcode != 1 {
+		t.Errorf("timeout resulted in %d, %v", exitcode, err)
+	}
+
+	if received.Context().Err() == nil {
+		t.Errorf("context wasn't released after the run")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	exitcode, err = a.RunContext(ctx, []string{"wait"})
+	if !errors.Is(err, context.Canceled) || exitcode != ExitInterrupted {
+		t.Errorf("cancellation resulted in %d, %v", exitcode, err)
+	}
+
+	a.RunArgs([]string{"help", "wait"})
+	if !strings.Contains(output.String(), "--timeout=duration") {
+		t.Errorf("timeout option is not listed:\n%s", output.String())
+	}
+
+	if !strings.Contains(output.String(), "Usage: wait [--timeout]") {
+		t.Errorf("timeout option is not in the usage:\n%s", output.String())
+	}
+}
+
+func TestRunShell_Interrupted(t *testing.T) {
+	var ran []string
+	a := newTestApp("app")
+	a.Shell = &Shell{}
+	a.AddCommand(Command{
+		Name: "wait",
+		HandleErr: func(ctx Context) error {
+			ran = append(ran, "wait")
+			syscall.Kill(os.Getpid(), syscall.SIGINT)
+			<-ctx.Context().Done()
+			return ctx.Context().Err()
+		},
+	})
+	a.AddCommand(Command{
+		Name: "run",
+		Handle: func(ctx Context) int {
+			if ctx.Context().Err() != nil {
+				t.Errorf("command after the interrupted one is cancelled")
+			}
+
+			ran = append(ran, "run")
+			return 0
+		},
+	})
+	a.Stdin = strings.NewReader("wait\nrun\nexit 2\n")
+	defer output.Reset()
+
+	ctx, interruption := a.notifySignals()
+	defer interruption.stop()
+
+	if exitcode := a.RunShell(ctx); exitcode != 2 {
+		t.Errorf("interrupted shell resulted in exit code %d", exitcode)
+	}
+
+	if strings.Join(ran, ",") != "wait,run" || interruption.interrupted() {
+		t.Errorf("shell ran %q, interrupted: %v", ran, interruption.interrupted())
+	}
+
+	if strings.Contains(output.String(), "canceled") {
+		t.Errorf("interrupted command is reported:\n%s", output.String())
+	}
+}
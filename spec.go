@@ -0,0 +1,402 @@
+package climax
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// SpecSchema is the version of the specification format. It's
+// bumped on every incompatible change of the format.
+const SpecSchema = 1
+
+// Spec is a machine-readable description of the command line
+// interface of the application, meant to be serialized to JSON.
+//
+// It covers everything but the code: handlers, hooks, middleware,
+// completion functions, error formatting and mapping, and the
+// configuration format, as well as the streams.
+type Spec struct {
+	Schema    int           `json:"schema"`
+	Name      string        `json:"name"`
+	Brief     string        `json:"brief,omitempty"`
+	Version   string        `json:"version,omitempty"`
+	EnvPrefix string        `json:"envPrefix,omitempty"`
+	Config    string        `json:"config,omitempty"`
+	Groups    []string      `json:"groups,omitempty"`
+	Flags     []FlagSpec    `json:"flags,omitempty"`
+	Commands  []CommandSpec `json:"commands,omitempty"`
+	Topics    []TopicSpec   `json:"topics,omitempty"`
+
+	Shell              *ShellSpec         `json:"shell,omitempty"`
+	Scripts            bool               `json:"scripts,omitempty"`
+	ResponseFiles      *ResponseFilesSpec `json:"responseFiles,omitempty"`
+	PrefixMatching     bool               `json:"prefixMatching,omitempty"`
+	SuggestionDistance int                `json:"suggestionDistance,omitempty"`
+	RecoverPanics      bool               `json:"recoverPanics,omitempty"`
+	CrashDir           string             `json:"crashDir,omitempty"`
+
+	// GracePeriod is a duration the way time.ParseDuration
+	// takes it, e.g. "5s".
+	GracePeriod string `json:"gracePeriod,omitempty"`
+}
+
+// CommandSpec describes a Command.
+type CommandSpec struct {
+	Name        string         `json:"name"`
+	Aliases     []string       `json:"aliases,omitempty"`
+	Brief       string         `json:"brief,omitempty"`
+	Usage       string         `json:"usage,omitempty"`
+	Help        string         `json:"help,omitempty"`
+	Group       string         `json:"group,omitempty"`
+	Flags       []FlagSpec     `json:"flags,omitempty"`
+	TimeoutFlag bool           `json:"timeoutFlag,omitempty"`
+	Arguments   []ArgumentSpec `json:"arguments,omitempty"`
+	Examples    []ExampleSpec  `json:"examples,omitempty"`
+	Commands    []CommandSpec  `json:"commands,omitempty"`
+}
+
+// FlagSpec describes a Flag.
+type FlagSpec struct {
+	Name        string   `json:"name"`
+	Short       string   `json:"short,omitempty"`
+	Usage       string   `json:"usage,omitempty"`
+	Help        string   `json:"help,omitempty"`
+	Variable    bool     `json:"variable,omitempty"`
+	Type        FlagType `json:"type"`
+	Default     string   `json:"default,omitempty"`
+	Repeatable  bool     `json:"repeatable,omitempty"`
+	SplitCommas bool     `json:"splitCommas,omitempty"`
+	Required    bool     `json:"required,omitempty"`
+	Inherited   bool     `json:"inherited,omitempty"`
+	Env         []string `json:"env,omitempty"`
+}
+
+// ArgumentSpec describes an Argument.
+type ArgumentSpec struct {
+	Name     string `json:"name"`
+	Help     string `json:"help,omitempty"`
+	Optional bool   `json:"optional,omitempty"`
+	Variadic bool   `json:"variadic,omitempty"`
+	Min      int    `json:"min,omitempty"`
+	Max      int    `json:"max,omitempty"`
+}
+
+// ExampleSpec describes an Example.
+type ExampleSpec struct {
+	Usecase     string `json:"usecase"`
+	Description string `json:"description,omitempty"`
+}
+
+// TopicSpec describes a Topic.
+type TopicSpec struct {
+	Name  string `json:"name"`
+	Brief string `json:"brief,omitempty"`
+	Text  string `json:"text,omitempty"`
+}
+
+// ShellSpec describes a Shell.
+type ShellSpec struct {
+	Prompt  string `json:"prompt,omitempty"`
+	History string `json:"history,omitempty"`
+}
+
+// ResponseFilesSpec describes ResponseFiles.
+type ResponseFilesSpec struct {
+	Lines    bool `json:"lines,omitempty"`
+	MaxDepth int  `json:"maxDepth,omitempty"`
+}
+
+// Spec describes the application.
+func (a *Application) Spec() Spec {
+	spec := Spec{
+		Schema:             SpecSchema,
+		Name:               a.Name,
+		Brief:              a.Brief,
+		Version:            a.Version,
+		EnvPrefix:          a.EnvPrefix,
+		Flags:              flagSpecs(a.Flags),
+		Commands:           commandSpecs(a.Commands),
+		Scripts:            a.Scripts,
+		PrefixMatching:     a.PrefixMatching,
+		SuggestionDistance: a.SuggestionDistance,
+		RecoverPanics:      a.RecoverPanics,
+		CrashDir:           a.CrashDir,
+	}
+
+	if a.Config != nil {
+		spec.Config = a.Config.Path
+	}
+
+	if a.Shell != nil {
+		spec.Shell = &ShellSpec{a.Shell.Prompt, a.Shell.History}
+	}
+
+	if a.ResponseFiles != nil {
+		spec.ResponseFiles = &ResponseFilesSpec{a.ResponseFiles.Lines, a.ResponseFiles.MaxDepth}
+	}
+
+	if a.GracePeriod != 0 {
+		spec.GracePeriod = a.GracePeriod.String()
+	}
+
+	for _, group := range a.Groups {
+		spec.Groups = append(spec.Groups, group.Name)
+	}
+
+	for _, topic := range a.Topics {
+		spec.Topics = append(spec.Topics, TopicSpec{topic.Name, topic.Brief, topic.Text})
+	}
+
+	return spec
+}
+
+func flagSpecs(flags []Flag) []FlagSpec {
+	var specs []FlagSpec
+	for _, flag := range flags {
+		specs = append(specs, FlagSpec{
+			Name:        flag.Name,
+			Short:       flag.Short,
+			Usage:       flag.Usage,
+			Help:        flag.Help,
+			Variable:    flag.Variable,
+			Type:        flag.Type,
+			Default:     flag.Default,
+			Repeatable:  flag.Repeatable,
+			SplitCommas: flag.SplitCommas,
+			Required:    flag.Required,
+			Inherited:   flag.Inherited,
+			Env:         flag.Env,
+		})
+	}
+
+	return specs
+}
+
+func commandSpecs(commands []Command) []CommandSpec {
+	var specs []CommandSpec
+	for _, command := range commands {
+		spec := CommandSpec{
+			Name:        command.Name,
+			Aliases:     command.Aliases,
+			Brief:       command.Brief,
+			Usage:       command.Usage,
+			Help:        command.Help,
+			Group:       command.Group,
+			Flags:       flagSpecs(command.Flags),
+			TimeoutFlag: command.TimeoutFlag,
+			Commands:    commandSpecs(command.Commands),
+		}
+
+		for _, argument := range command.Arguments {
+			spec.Arguments = append(spec.Arguments, ArgumentSpec{
+				Name:     argument.Name,
+				Help:     argument.Help,
+				Optional: argument.Optional,
+				Variadic: argument.Variadic,
+				Min:      argument.Min,
+				Max:      argument.Max,
+			})
+		}
+
+		for _, example := range command.Examples {
+			spec.Examples = append(spec.Examples, ExampleSpec{example.Usecase, example.Description})
+		}
+
+		specs = append(specs, spec)
+	}
+
+	return specs
+}
+
+// WriteSpec writes the JSON specification of the application.
+func (a *Application) WriteSpec(w io.Writer) error {
+	data, err := json.MarshalIndent(a.Spec(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// ReadSpec builds an application from the JSON specification.
+//
+// The application has no handlers yet, attach them by command
+// names with Handle or HandleErr afterwards.
+func ReadSpec(r io.Reader) (*Application, error) {
+	var spec Spec
+
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&spec); err != nil {
+		return nil, fmt.Errorf("spec: %w", err)
+	}
+
+	return FromSpec(spec)
+}
+
+// FromSpec builds an application from the specification.
+func FromSpec(spec Spec) (*Application, error) {
+	if spec.Schema != SpecSchema {
+		return nil, fmt.Errorf("spec: unsupported schema %d, expected %d", spec.Schema, SpecSchema)
+	}
+
+	if spec.Name == "" {
+		return nil, fmt.Errorf("spec: application name is missing")
+	}
+
+	a := New(spec.Name)
+	a.Brief = spec.Brief
+	a.Version = spec.Version
+	a.EnvPrefix = spec.EnvPrefix
+	a.Flags = specFlags(spec.Flags)
+	a.Scripts = spec.Scripts
+	a.PrefixMatching = spec.PrefixMatching
+	a.SuggestionDistance = spec.SuggestionDistance
+	a.RecoverPanics = spec.RecoverPanics
+	a.CrashDir = spec.CrashDir
+
+	if spec.Config != "" {
+		a.Config = &Config{Path: spec.Config}
+	}
+
+	if spec.Shell != nil {
+		a.Shell = &Shell{Prompt: spec.Shell.Prompt, History: spec.Shell.History}
+	}
+
+	if spec.ResponseFiles != nil {
+		a.ResponseFiles = &ResponseFiles{Lines: spec.ResponseFiles.Lines, MaxDepth: spec.ResponseFiles.MaxDepth}
+	}
+
+	if spec.GracePeriod != "" {
+		period, err := time.ParseDuration(spec.GracePeriod)
+		if err != nil {
+			return nil, fmt.Errorf("spec: invalid grace period %q", spec.GracePeriod)
+		}
+
+		a.GracePeriod = period
+	}
+
+	for _, group := range spec.Groups {
+		a.AddGroup(group)
+	}
+
+	for _, topic := range spec.Topics {
+		a.AddTopic(Topic{Name: topic.Name, Brief: topic.Brief, Text: topic.Text})
+	}
+
+	for _, command := range spec.Commands {
+		if command.Group != "" && a.groupByName(command.Group) == nil {
+			return nil, fmt.Errorf("spec: command %s refers to unknown group %q", command.Name, command.Group)
+		}
+
+		a.AddCommand(specCommand(command))
+	}
+
+	return a, nil
+}
+
+func specFlags(specs []FlagSpec) []Flag {
+	var flags []Flag
+	for _, spec := range specs {
+		flags = append(flags, Flag{
+			Name:        spec.Name,
+			Short:       spec.Short,
+			Usage:       spec.Usage,
+			Help:        spec.Help,
+			Variable:    spec.Variable,
+			Type:        spec.Type,
+			Default:     spec.Default,
+			Repeatable:  spec.Repeatable,
+			SplitCommas: spec.SplitCommas,
+			Required:    spec.Required,
+			Inherited:   spec.Inherited,
+			Env:         spec.Env,
+		})
+	}
+
+	return flags
+}
+
+func specCommand(spec CommandSpec) Command {
+	command := Command{
+		Name:        spec.Name,
+		Aliases:     spec.Aliases,
+		Brief:       spec.Brief,
+		Usage:       spec.Usage,
+		Help:        spec.Help,
+		Group:       spec.Group,
+		Flags:       specFlags(spec.Flags),
+		TimeoutFlag: spec.TimeoutFlag,
+	}
+
+	for _, argument := range spec.Arguments {
+		command.Arguments = append(command.Arguments, Argument{
+			Name:     argument.Name,
+			Help:     argument.Help,
+			Optional: argument.Optional,
+			Variadic: argument.Variadic,
+			Min:      argument.Min,
+			Max:      argument.Max,
+		})
+	}
+
+	for _, example := range spec.Examples {
+		command.AddExample(Example{Usecase: example.Usecase, Description: example.Description})
+	}
+
+	for _, subcommand := range spec.Commands {
+		command.AddCommand(specCommand(subcommand))
+	}
+
+	return command
+}
+
+// Handle attaches the handler to the command with the full name
+// given, e.g. "remote add". It's meant for the applications built
+// from specifications.
+func (a *Application) Handle(name string, handler CmdHandler) error {
+	command, err := a.commandByFullName(name)
+	if err != nil {
+		return err
+	}
+
+	command.Handle, command.HandleErr = handler, nil
+	return nil
+}
+
+// HandleErr is Handle for the error-returning handlers.
+func (a *Application) HandleErr(name string, handler CmdErrHandler) error {
+	command, err := a.commandByFullName(name)
+	if err != nil {
+		return err
+	}
+
+	command.Handle, command.HandleErr = nil, handler
+	return nil
+}
+
+// commandByFullName returns the command with the full name given,
+// e.g. "remote add".
+func (a *Application) commandByFullName(name string) (*Command, error) {
+	commands := a.Commands
+
+	var command *Command
+	for _, each := range strings.Fields(name) {
+		command = commandByName(commands, each)
+		if command == nil {
+			return nil, &UnknownCommandError{Name: name}
+		}
+
+		commands = command.Commands
+	}
+
+	if command == nil {
+		return nil, &UnknownCommandError{Name: name}
+	}
+
+	return command, nil
+}
@@ -0,0 +1,118 @@
+package climax
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSpec(t *testing.T) {
+	a := newGitApp()
+	a.Version = "2.0"
+	a.Config = &Config{Path: "~/.gitconfig.json"}
+	a.Commands[1].Commands[0].Flags = []Flag{
+		{Name: "tags", Type: IntFlag, Default: "1", Repeatable: true, Env: []string{"GIT_TAGS"}},
+	}
+	a.Commands[1].Commands[0].TimeoutFlag = true
+	a.Shell = &Shell{Prompt: "git$ ", History: "~/.git_history"}
+	a.Scripts = true
+	a.ResponseFiles = &ResponseFiles{Lines: true}
+	a.PrefixMatching = true
+	a.SuggestionDistance = 3
+	a.GracePeriod = 5 * time.Second
+	a.RecoverPanics = true
+
+	var b bytes.Buffer
+	if err := a.WriteSpec(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, fragment := range []string{`"schema": 1`, `"type": "int"`, `"usecase": "origin git://host/repo"`, `"timeoutFlag": true`, `"gracePeriod": "5s"`} {
+		if !strings.Contains(b.String(), fragment) {
+			t.Errorf("spec lacks %s:\n%s", fragment, b.String())
+		}
+	}
+
+	loaded, err := ReadSpec(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(loaded.Spec(), a.Spec()) {
+		t.Errorf("spec didn't survive the round trip:\n%+v\n%+v", loaded.Spec(), a.Spec())
+	}
+
+	if loaded.globalHelp() != a.globalHelp() {
+		t.Errorf("loaded application help is different:\n%s", loaded.globalHelp())
+	}
+}
+
+func TestReadSpec(t *testing.T) {
+	const spec = `{
+		"schema": 1,
+		"name": "git",
+		"commands": [{
+			"name": "remote",
+			"commands": [{
+				"name": "add",
+				"flags": [{"name": "fetch", "short": "f", "type": "bool"}],
+				"arguments": [{"name": "name"}]
+			}]
+		}]
+	}`
+
+	a, err := ReadSpec(strings.NewReader(spec))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a.Stdout, a.Stderr = &output, &output
+	defer output.Reset()
+
+	var received Context
+	if err := a.Handle("remote add", func(ctx Context) int {
+		received = ctx
+		return 0
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := a.RunArgs([]string{"remote", "add", "-f", "origin"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !received.Is("fetch") || received.Args[0] != "origin" {
+		t.Errorf("handler received unexpected context:\n%s", received)
+	}
+
+	if err := a.Handle("remote rename", nil); err == nil {
+		t.Errorf("handler was attached to unknown command")
+	}
+
+	if err := a.HandleErr("remote add", func(ctx Context) error {
+		return &ExitError{Code: 3}
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if exitcode, _ := a.RunArgs([]string{"remote", "add", "origin"}); exitcode != 3 {
+		t.Errorf("error handler resulted in exit code %d", exitcode)
+	}
+
+	failures := []string{
+		`{"schema": 2, "name": "git"}`,
+		`{"schema": 1}`,
+		`{"schema": 1, "name": "git", "unknown": true}`,
+		`{"schema": 1, "name": "git", "flags": [{"name": "x", "type": "complex"}]}`,
+		`{"schema": 1, "name": "git", "commands": [{"name": "x", "group": "missing"}]}`,
+		`{"schema": 1, "name": "git", "gracePeriod": "soon"}`,
+	}
+
+	for _, failure := range failures {
+		if _, err := ReadSpec(strings.NewReader(failure)); err == nil {
+			t.Errorf("%s didn't fail", failure)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package climax
+
+import (
+	"sort"
+	"strings"
+)
+
+// defaultSuggestionDistance is used when Application doesn't set one.
+const defaultSuggestionDistance = 2
+
+// distance is the optimal string alignment distance between the
+// strings: the number of insertions, deletions, substitutions and
+// transpositions of adjacent characters turning one into another.
+func distance(a, b string) int {
+	s, t := []rune(a), []rune(b)
+
+	d := make([][]int, len(s)+1)
+	for i := range d {
+		d[i] = make([]int, len(t)+1)
+		d[i][0] = i
+	}
+
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(s); i++ {
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+
+			d[i][j] = minimum(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+
+			if i > 1 && j > 1 && s[i-1] == t[j-2] && s[i-2] == t[j-1] {
+				d[i][j] = minimum(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+
+	return d[len(s)][len(t)]
+}
+
+func minimum(first int, rest ...int) int {
+	for _, n := range rest {
+		if n < first {
+			first = n
+		}
+	}
+
+	return first
+}
+
+// suggest picks the candidates resembling the name: the ones within
+// the suggestion distance of the application, or starting with the
+// name, closest first.
+func (a *Application) suggest(name string, candidates []string) []string {
+	threshold := a.SuggestionDistance
+	if threshold == 0 {
+		threshold = defaultSuggestionDistance
+	}
+
+	if threshold < 0 || name == "" {
+		return nil
+	}
+
+	distances := make(map[string]int)
+	for _, candidate := range candidates {
+		d := distance(strings.ToLower(name), strings.ToLower(candidate))
+		if d <= threshold || strings.HasPrefix(candidate, name) {
+			distances[candidate] = d
+		}
+	}
+
+	var suggestions []string
+	for candidate := range distances {
+		suggestions = append(suggestions, candidate)
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		di, dj := distances[suggestions[i]], distances[suggestions[j]]
+		if di != dj {
+			return di < dj
+		}
+
+		return suggestions[i] < suggestions[j]
+	})
+
+	return suggestions
+}
+
+// didYouMean formats the suggestions to be appended to an error.
+func didYouMean(suggestions []string) string {
+	switch len(suggestions) {
+	case 0:
+		return ""
+	case 1:
+		return "\n\nDid you mean this?\n\t" + suggestions[0]
+	}
+
+	return "\n\nDid you mean one of these?\n\t" + strings.Join(suggestions, "\n\t")
+}
+
+func commandNames(commands []Command) []string {
+	var names []string
+	for _, command := range commands {
+		names = append(names, command.names()...)
+	}
+
+	return names
+}
+
+// unknownCommand reports the name missing among the commands.
+func (a *Application) unknownCommand(name, parent string, commands []Command) error {
+	candidates := commandNames(commands)
+	if parent == "" {
+		candidates = append(candidates, a.builtins()...)
+	}
+
+	fullName := strings.TrimSpace(parent + " " + name)
+	return &UnknownCommandError{
+		Name:        fullName,
+		Suggestions: a.suggest(name, candidates),
+	}
+}
+
+// unknownTopic reports the name missing among both commands and
+// topics. For the path of commands, the suggestions are the ones
+// for the first unknown name among the subcommands of the path.
+func (a *Application) unknownTopic(names []string) error {
+	candidates := commandNames(a.Commands)
+	for _, topic := range a.topics() {
+		candidates = append(candidates, topic.Name)
+	}
+
+	// $ program help remote ad
+	//                       ^ the first unknown name
+	prefix, commands := "", a.Commands
+	for _, name := range names[:len(names)-1] {
+		name, err := a.resolveCommand(commands, name)
+		command := commandByName(commands, name)
+		if err != nil || command == nil {
+			break
+		}
+
+		prefix += command.Name + " "
+		commands = command.Commands
+		candidates = commandNames(commands)
+	}
+
+	var suggestions []string
+	for _, suggestion := range a.suggest(names[len(strings.Fields(prefix))], candidates) {
+		suggestions = append(suggestions, prefix+suggestion)
+	}
+
+	return &UnknownTopicError{
+		Name:        strings.Join(names, " "),
+		Suggestions: suggestions,
+	}
+}
+
+// unknownFlag reports the name missing among the flags.
+func (a *Application) unknownFlag(name string, flags []Flag) error {
+	var candidates []string
+	for _, flag := range flags {
+		candidates = append(candidates, flag.Name)
+	}
+
+	var suggestions []string
+	for _, suggestion := range a.suggest(name, candidates) {
+		suggestions = append(suggestions, "--"+suggestion)
+	}
+
+	return &FlagError{
+		Name:        name,
+		Reason:      "does not exist",
+		Suggestions: suggestions,
+	}
+}
@@ -0,0 +1,90 @@
+package climax
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDistance(t *testing.T) {
+	cases := []struct {
+		a, b     string
+		expected int
+	}{
+		{"", "", 0},
+		{"remote", "remote", 0},
+		{"", "init", 4},
+		{"remtoe", "remote", 1},
+		{"remot", "remote", 1},
+		{"rmeote", "remote", 1},
+		{"kitten", "sitting", 3},
+		{"ca", "abc", 3},
+	}
+
+	for _, c := range cases {
+		if actual := distance(c.a, c.b); actual != c.expected {
+			t.Errorf("distance(%q, %q) = %d, expected %d", c.a, c.b, actual, c.expected)
+		}
+	}
+}
+
+func TestSuggestions(t *testing.T) {
+	var handled string
+	a := newNestedApp(&handled)
+	a.AddFlag(Flag{Name: "verbose", Short: "v"})
+	a.AddTopic(Topic{Name: "workflows", Brief: "how to collaborate"})
+	defer output.Reset()
+
+	cases := []struct {
+		arguments []string
+		expected  []string
+	}{
+		{[]string{"remtoe"}, []string{"remote"}},
+		{[]string{"hlep"}, []string{"help"}},
+		{[]string{"remote", "lsit"}, []string{"list"}},
+		{[]string{"remote", "a"}, []string{"add"}},
+		{[]string{"help", "workflow"}, []string{"workflows"}},
+		{[]string{"help", "remote", "ad"}, []string{"remote add"}},
+		{[]string{"help", "remot", "add"}, []string{"remote"}},
+		{[]string{"--verbos", "init"}, []string{"--verbose"}},
+		{[]string{"init", "--vrebose"}, []string{"--verbose"}},
+		{[]string{"xyzzy"}, nil},
+	}
+
+	for _, c := range cases {
+		_, err := a.RunArgs(c.arguments)
+
+		var usage *UsageError
+		if errors.As(err, &usage) {
+			err = usage.Err
+		}
+
+		var suggestions []string
+		switch err := err.(type) {
+		case *UnknownCommandError:
+			suggestions = err.Suggestions
+		case *UnknownTopicError:
+			suggestions = err.Suggestions
+		case *FlagError:
+			suggestions = err.Suggestions
+		default:
+			t.Errorf("%q resulted in %v", c.arguments, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(suggestions, c.expected) {
+			t.Errorf("%q suggested %q, expected %q", c.arguments, suggestions, c.expected)
+		}
+	}
+
+	_, err := a.RunArgs([]string{"remtoe"})
+	if expected := "Did you mean this?\n\tremote"; !strings.HasSuffix(err.Error(), expected) {
+		t.Errorf("error doesn't suggest: %v", err)
+	}
+
+	a.SuggestionDistance = -1
+	if _, err := a.RunArgs([]string{"remtoe"}); err.(*UnknownCommandError).Suggestions != nil {
+		t.Errorf("disabled suggestions were made: %v", err)
+	}
+}
@@ -0,0 +1,205 @@
+package climax
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// lineEditor edits the line of the shell on the terminal in raw
+// mode: it echoes the input itself, completes the word being typed
+// and walks the history.
+type lineEditor struct {
+	app     *Application
+	prompt  string
+	history []string
+
+	line []rune
+
+	// recalled is the index of the history line being edited,
+	// typed keeps the line typed before walking the history.
+	recalled int
+	typed    []rune
+}
+
+// Control characters the editor handles.
+const (
+	keyInterrupt = 0x03 // ^C
+	keyEOF       = 0x04 // ^D
+	keyBackspace = 0x08 // ^H
+	keyTab       = '\t'
+	keyKill      = 0x15 // ^U
+	keyEscape    = 0x1b
+	keyDelete    = 0x7f
+)
+
+func newLineEditor(app *Application, prompt string, history []string) *lineEditor {
+	return &lineEditor{app: app, prompt: prompt, history: history, recalled: len(history)}
+}
+
+// edit reads the keys until the line is entered and returns it.
+// ^D on the empty line ends the input.
+func (e *lineEditor) edit(reader *bufio.Reader) (string, error) {
+	e.redraw()
+
+	for {
+		r, _, err := reader.ReadRune()
+		if err != nil {
+			return string(e.line), err
+		}
+
+		switch r {
+		case '\r', '\n':
+			e.app.println()
+			return string(e.line), nil
+
+		case keyInterrupt:
+			// ^C drops the line, like SIGINT does at the prompt
+			// of the shell without the editor.
+			e.app.printf("^C\n")
+			e.line, e.recalled = nil, len(e.history)
+
+		case keyEOF:
+			if len(e.line) == 0 {
+				return "", io.EOF
+			}
+
+			continue
+
+		case keyBackspace, keyDelete:
+			if len(e.line) > 0 {
+				e.line = e.line[:len(e.line)-1]
+			}
+
+		case keyKill:
+			e.line = nil
+
+		case keyTab:
+			e.complete()
+
+		case keyEscape:
+			e.escape(reader)
+
+		default:
+			if r >= ' ' {
+				e.line = append(e.line, r)
+				e.app.printf("%c", r)
+			}
+
+			continue
+		}
+
+		e.redraw()
+	}
+}
+
+// redraw prints the prompt and the line anew.
+func (e *lineEditor) redraw() {
+	e.app.printf("\r\x1b[K%s%s", e.prompt, string(e.line))
+}
+
+// escape handles the escape sequence: the up and down arrows walk
+// the history, while the rest of the sequences are ignored.
+func (e *lineEditor) escape(reader *bufio.Reader) {
+	if r, _, err := reader.ReadRune(); err != nil || r != '[' && r != 'O' {
+		return
+	}
+
+	// The parameters of the sequence precede its final character.
+	var final rune
+	for {
+		r, _, err := reader.ReadRune()
+		if err != nil {
+			return
+		}
+
+		if r >= 0x40 && r <= 0x7e {
+			final = r
+			break
+		}
+	}
+
+	switch {
+	case final == 'A' && e.recalled > 0:
+		if e.recalled == len(e.history) {
+			e.typed = e.line
+		}
+
+		e.recalled--
+		e.line = []rune(e.history[e.recalled])
+
+	case final == 'B' && e.recalled < len(e.history):
+		e.recalled++
+		if e.recalled == len(e.history) {
+			e.line = e.typed
+		} else {
+			e.line = []rune(e.history[e.recalled])
+		}
+	}
+}
+
+// complete completes the last word of the line in place: the only
+// candidate replaces it, while several ones extend it up to their
+// common prefix, or get listed if there is nothing to extend.
+func (e *lineEditor) complete() {
+	line := string(e.line)
+
+	words, err := splitWords(line)
+	if err != nil {
+		return
+	}
+
+	start := lastWordStart(line)
+	if start == len(line) {
+		words = append(words, "")
+	}
+
+	candidates := e.app.lineCandidates(words)
+	word := words[len(words)-1]
+
+	switch {
+	case len(candidates) == 0:
+		return
+
+	case len(candidates) == 1:
+		value := candidates[0].Value
+		completed := line[:start] + quoteWord(value)
+		if !strings.HasSuffix(value, "=") {
+			completed += " "
+		}
+
+		e.line = []rune(completed)
+
+	default:
+		prefix := candidates[0].Value
+		for _, candidate := range candidates[1:] {
+			for !strings.HasPrefix(candidate.Value, prefix) {
+				prefix = prefix[:len(prefix)-1]
+			}
+		}
+
+		if len(prefix) > len(word) && quoteWord(prefix) == prefix {
+			e.line = []rune(line[:start] + prefix)
+			return
+		}
+
+		e.app.println()
+		for _, candidate := range candidates {
+			if candidate.Description != "" {
+				e.app.printf("%-11s %s\n", candidate.Value, candidate.Description)
+			} else {
+				e.app.println(candidate.Value)
+			}
+		}
+	}
+}
+
+// quoteWord quotes the word for the line of the shell, unless
+// it's made of the safe characters alone.
+func quoteWord(word string) string {
+	if word != "" && strings.Trim(word, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_=+.,:/@%") == "" {
+		return word
+	}
+
+	return shellQuote(word, false)
+}
@@ -0,0 +1,12 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+// +build !linux,!darwin,!dragonfly,!freebsd,!netbsd,!openbsd
+
+package climax
+
+import "errors"
+
+// makeRaw isn't supported, so the shell reads the lines the way
+// the terminal gives them.
+func makeRaw(fd uintptr) (func(), error) {
+	return nil, errors.New("raw mode is not supported")
+}
@@ -0,0 +1,40 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+// +build linux darwin dragonfly freebsd netbsd openbsd
+
+package climax
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// makeRaw puts the terminal into raw mode, so the keys are read
+// one by one, without echo and signals, and returns the function
+// restoring the previous mode. It fails if fd is no terminal.
+func makeRaw(fd uintptr) (func(), error) {
+	var previous syscall.Termios
+	if err := ioctlTermios(fd, ioctlGetTermios, &previous); err != nil {
+		return nil, err
+	}
+
+	raw := previous
+	raw.Lflag &^= syscall.ICANON | syscall.ECHO | syscall.ISIG | syscall.IEXTEN
+	raw.Iflag &^= syscall.IXON
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+
+	if err := ioctlTermios(fd, ioctlSetTermios, &raw); err != nil {
+		return nil, err
+	}
+
+	return func() { ioctlTermios(fd, ioctlSetTermios, &previous) }, nil
+}
+
+func ioctlTermios(fd, request uintptr, termios *syscall.Termios) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, request, uintptr(unsafe.Pointer(termios)))
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
+}
@@ -0,0 +1,168 @@
+package climax
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FlagType is a kind of value a variable flag accepts.
+//
+// Climax checks the values of typed flags while parsing the
+// command line, so Context accessors like GetInt never come
+// across a malformed value.
+type FlagType int
+
+const (
+	// StringFlag accepts any value, it's the default one.
+	StringFlag FlagType = iota
+
+	// IntFlag accepts a decimal, hex (0x) or octal (0) integer.
+	IntFlag
+
+	// FloatFlag accepts a floating-point number.
+	FloatFlag
+
+	// BoolFlag accepts true/false, 1/0, yes/no and on/off.
+	// Unlike other flags, its value can be omitted: --debug
+	// is the same as --debug=true. For the same reason, the
+	// value is never taken from the next word, so it has to
+	// be given with =, e.g. --debug=false, while --debug false
+	// sets the flag and passes false on as an argument.
+	BoolFlag
+
+	// DurationFlag accepts a time.ParseDuration string, e.g. 1m30s.
+	DurationFlag
+
+	// SizeFlag accepts a size in bytes with an optional unit
+	// suffix: k, m, g, t (powers of 1000) or ki, mi, gi, ti
+	// (powers of 1024), e.g. 512, 10k, 1.5GiB.
+	SizeFlag
+)
+
+func (t FlagType) String() string {
+	switch t {
+	case IntFlag:
+		return "int"
+	case FloatFlag:
+		return "float"
+	case BoolFlag:
+		return "bool"
+	case DurationFlag:
+		return "duration"
+	case SizeFlag:
+		return "size"
+	}
+
+	return "string"
+}
+
+// MarshalText encodes the type as its name, e.g. "int".
+func (t FlagType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText decodes the type from its name, e.g. "int".
+func (t *FlagType) UnmarshalText(text []byte) error {
+	for kind := StringFlag; kind <= SizeFlag; kind++ {
+		if kind.String() == string(text) {
+			*t = kind
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown flag type %q", text)
+}
+
+// check returns an error if value can't be converted to the type.
+func (t FlagType) check(value string) error {
+	var err error
+
+	switch t {
+	case IntFlag:
+		_, err = parseInt(value)
+	case FloatFlag:
+		_, err = strconv.ParseFloat(value, 64)
+	case BoolFlag:
+		_, err = parseBool(value)
+	case DurationFlag:
+		_, err = time.ParseDuration(value)
+	case SizeFlag:
+		_, err = parseSize(value)
+	}
+
+	if err != nil {
+		return fmt.Errorf("has invalid value %q: expected %s", value, t)
+	}
+
+	return nil
+}
+
+func parseInt(value string) (int, error) {
+	n, err := strconv.ParseInt(value, 0, strconv.IntSize)
+	return int(n), err
+}
+
+func parseBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "1", "t", "true", "y", "yes", "on":
+		return true, nil
+	case "0", "f", "false", "n", "no", "off":
+		return false, nil
+	}
+
+	return false, fmt.Errorf("invalid boolean %q", value)
+}
+
+var sizeUnits = map[string]float64{
+	"":   1,
+	"k":  1e3,
+	"m":  1e6,
+	"g":  1e9,
+	"t":  1e12,
+	"p":  1e15,
+	"ki": 1 << 10,
+	"mi": 1 << 20,
+	"gi": 1 << 30,
+	"ti": 1 << 40,
+	"pi": 1 << 50,
+}
+
+func parseSize(value string) (int64, error) {
+	s := strings.ToLower(strings.TrimSpace(value))
+
+	split := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if split < 0 {
+		split = len(s)
+	}
+
+	number, unit := s[:split], strings.TrimSpace(s[split:])
+	if unit != "b" {
+		unit = strings.TrimSuffix(unit, "b")
+	}
+	if unit == "b" {
+		unit = ""
+	}
+
+	multiplier, ok := sizeUnits[unit]
+	if !ok || number == "" {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+
+	bytes := n * multiplier
+	// MaxInt64 rounds up to 2^63 as float64, which overflows int64.
+	if bytes >= math.MaxInt64 {
+		return 0, fmt.Errorf("size %q is too large", value)
+	}
+
+	return int64(bytes), nil
+}
@@ -0,0 +1,200 @@
+package climax
+
+import (
+	"fmt"
+	"strings"
+)
+
+// QuoteError is returned when the line ends inside a quoted
+// string or right after the backslash.
+type QuoteError struct {
+	Line string
+}
+
+func (e *QuoteError) Error() string {
+	return fmt.Sprintf("unterminated quote in %q", e.Line)
+}
+
+// splitWords splits the line into words the way POSIX shells do:
+// words are separated by spaces, unless they are quoted. Within
+// single quotes every character is literal, within double ones
+// the backslash escapes ", \, $ and `, while outside of the quotes
+// it escapes any character.
+func splitWords(line string) ([]string, error) {
+	return expandWords(line, nil)
+}
+
+// expandWords splits the line like splitWords does, also replacing
+// $NAME and ${NAME} outside of single quotes with the values the
+// expand function gives, if any.
+func expandWords(line string, expand func(name string) (string, error)) ([]string, error) {
+	var (
+		words  []string
+		word   strings.Builder
+		inWord bool
+	)
+
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+
+		switch {
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+
+			continue
+
+		case r == '\\':
+			i++
+			if i >= len(runes) {
+				return nil, &QuoteError{line}
+			}
+
+			word.WriteRune(runes[i])
+
+		case r == '\'':
+			end := indexRune(runes, i+1, '\'')
+			if end < 0 {
+				return nil, &QuoteError{line}
+			}
+
+			word.WriteString(string(runes[i+1 : end]))
+			i = end
+
+		case r == '$' && expand != nil:
+			value, end, err := expandVariable(runes, i, expand)
+			if err != nil {
+				return nil, err
+			}
+
+			word.WriteString(value)
+			i = end
+
+		case r == '"':
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '$' && expand != nil {
+					value, end, err := expandVariable(runes, i, expand)
+					if err != nil {
+						return nil, err
+					}
+
+					word.WriteString(value)
+					i = end
+					continue
+				}
+
+				if runes[i] == '\\' && i+1 < len(runes) && strings.ContainsRune("\"\\$`", runes[i+1]) {
+					i++
+				}
+
+				word.WriteRune(runes[i])
+			}
+
+			if i >= len(runes) {
+				return nil, &QuoteError{line}
+			}
+
+		default:
+			word.WriteRune(r)
+		}
+
+		inWord = true
+	}
+
+	if inWord {
+		words = append(words, word.String())
+	}
+
+	return words, nil
+}
+
+// expandVariable expands the variable starting with $ at i-th
+// rune, returning its value and the index of its last rune. The
+// lone $ stands for itself.
+func expandVariable(runes []rune, i int, expand func(string) (string, error)) (string, int, error) {
+	start, end := i+1, i+1
+	braced := start < len(runes) && runes[start] == '{'
+	if braced {
+		start++
+		end = indexRune(runes, start, '}')
+		if end < 0 {
+			return "", 0, &QuoteError{string(runes)}
+		}
+	} else {
+		for end < len(runes) && isNameRune(runes[end], end == start) {
+			end++
+		}
+	}
+
+	name := string(runes[start:end])
+	if name == "" {
+		return "$", i, nil
+	}
+
+	value, err := expand(name)
+	if !braced {
+		end--
+	}
+
+	return value, end, err
+}
+
+func isNameRune(r rune, first bool) bool {
+	return r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || !first && r >= '0' && r <= '9'
+}
+
+func indexRune(runes []rune, from int, r rune) int {
+	for i := from; i < len(runes); i++ {
+		if runes[i] == r {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// lastWordStart returns the byte offset the last word of the line
+// starts at, the length of the line if it ends with a space.
+func lastWordStart(line string) int {
+	var (
+		start   = len(line)
+		inWord  bool
+		quote   rune
+		escaped bool
+	)
+
+	for i, r := range line {
+		switch {
+		case escaped:
+			escaped = false
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else if r == '\\' && quote == '"' {
+				escaped = true
+			}
+		case r == '\\':
+			escaped = true
+		case r == '\'' || r == '"':
+			quote = r
+		case r == ' ' || r == '\t':
+			inWord = false
+			continue
+		}
+
+		if !inWord {
+			start, inWord = i, true
+		}
+	}
+
+	if !inWord {
+		return len(line)
+	}
+
+	return start
+}
@@ -0,0 +1,88 @@
+package climax
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitWords(t *testing.T) {
+	cases := map[string][]string{
+		"":                         nil,
+		"  remote   add  ":         {"remote", "add"},
+		`add "origin master" x`:    {"add", "origin master", "x"},
+		`say 'it''s' "a \"b\" \n"`: {"say", "its", `a "b" \n`},
+		`a\ b c\\d`:                {"a b", `c\d`},
+		`empty "" ''`:              {"empty", "", ""},
+		`--message='oops`:          nil,
+		"tab\tseparated":           {"tab", "separated"},
+		`mixed"quo"'tes'`:          {"mixedquotes"},
+		`dollar "$HOME" '\$' "\$"`: {"dollar", "$HOME", `\$`, "$"},
+		`unicode 'привет мир' ünï`: {"unicode", "привет мир", "ünï"},
+		`trailing\`:                nil,
+		`"unterminated`:            nil,
+	}
+
+	for line, expected := range cases {
+		words, err := splitWords(line)
+		if expected == nil && line != "" {
+			if err == nil {
+				t.Errorf("%q didn't fail, resulted in %q", line, words)
+			}
+
+			continue
+		}
+
+		if err != nil || !reflect.DeepEqual(words, expected) {
+			t.Errorf("%q resulted in %q (%v), expected %q", line, words, err, expected)
+		}
+	}
+}
+
+func TestExpandWords(t *testing.T) {
+	variables := map[string]string{"A": "x y", "B_1": "z"}
+	expand := func(name string) (string, error) {
+		if value, ok := variables[name]; ok {
+			return value, nil
+		}
+
+		return "", &ArgumentError{name, "is not defined"}
+	}
+
+	cases := map[string][]string{
+		`$A`:                {"x y"},
+		`"$A-$B_1" '$A'`:    {"x y-z", "$A"},
+		`${A}b $ \$A "\$A"`: {"x yb", "$", "$A", "$A"},
+		`pre${B_1}post`:     {"prezpost"},
+	}
+
+	for line, expected := range cases {
+		words, err := expandWords(line, expand)
+		if err != nil || !reflect.DeepEqual(words, expected) {
+			t.Errorf("%q resulted in %q (%v), expected %q", line, words, err, expected)
+		}
+	}
+
+	for _, line := range []string{"$C", `"${C}"`, "${A"} {
+		if _, err := expandWords(line, expand); err == nil {
+			t.Errorf("%q didn't fail", line)
+		}
+	}
+}
+
+func TestLastWordStart(t *testing.T) {
+	cases := map[string]int{
+		"":                  0,
+		"remote ":           7,
+		"remote add":        7,
+		`add 'my origin`:    4,
+		`add "a \" b`:       4,
+		`add my\ origin`:    4,
+		`add 'my origin' x`: 16,
+	}
+
+	for line, expected := range cases {
+		if start := lastWordStart(line); start != expected {
+			t.Errorf("%q resulted in %d, expected %d", line, start, expected)
+		}
+	}
+}